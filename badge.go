@@ -0,0 +1,329 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BadgeStatus is the status shown by a build status badge
+type BadgeStatus string
+
+// Statuses shown by build status badges
+const (
+	BadgeStatusPassing  BadgeStatus = "passing"
+	BadgeStatusFailing  BadgeStatus = "failing"
+	BadgeStatusError    BadgeStatus = "error"
+	BadgeStatusCanceled BadgeStatus = "canceled"
+	BadgeStatusUnknown  BadgeStatus = "unknown"
+)
+
+// badgeLabel is the label on the left of the badges
+const badgeLabel = "build"
+
+// badgeStyles are the width in pixels and the color of the right part of the badges
+var badgeStyles = map[BadgeStatus]struct {
+	width int
+	color string
+}{
+	BadgeStatusPassing:  {53, "#4c1"},
+	BadgeStatusFailing:  {47, "#e05d44"},
+	BadgeStatusError:    {41, "#9f9f9f"},
+	BadgeStatusCanceled: {59, "#9f9f9f"},
+	BadgeStatusUnknown:  {61, "#9f9f9f"},
+}
+
+// badgeLabelWidth is the width in pixels of the left part of the badges
+const badgeLabelWidth = 37
+
+// BuildBadgeStatus returns the status shown by the badge of a build.
+// Builds which have not finished yet show as unknown.
+func BuildBadgeStatus(b *Build) BadgeStatus {
+	if b == nil || b.State == nil {
+		return BadgeStatusUnknown
+	}
+
+	switch *b.State {
+	case BuildStatePassed:
+		return BadgeStatusPassing
+	case BuildStateFailed:
+		return BadgeStatusFailing
+	case BuildStateErrored:
+		return BadgeStatusError
+	case BuildStateCanceled:
+		return BadgeStatusCanceled
+	default:
+		return BadgeStatusUnknown
+	}
+}
+
+// BranchBadgeStatus returns the status shown by the badge of a branch,
+// given by its last build
+func BranchBadgeStatus(b *Branch) BadgeStatus {
+	if b == nil {
+		return BadgeStatusUnknown
+	}
+	return BuildBadgeStatus(b.LastBuild)
+}
+
+// RenderBuildBadge renders the SVG badge of a build
+func RenderBuildBadge(b *Build) []byte {
+	return RenderBadge(BuildBadgeStatus(b))
+}
+
+// RenderBranchBadge renders the SVG badge of a branch
+func RenderBranchBadge(b *Branch) []byte {
+	return RenderBadge(BranchBadgeStatus(b))
+}
+
+// RenderBadge renders the SVG badge of the provided status,
+// looking like the badges served by Travis CI
+func RenderBadge(status BadgeStatus) []byte {
+	style, ok := badgeStyles[status]
+	if !ok {
+		status = BadgeStatusUnknown
+		style = badgeStyles[status]
+	}
+
+	width := badgeLabelWidth + style.width
+	labelX := float64(badgeLabelWidth) / 2
+	statusX := float64(badgeLabelWidth) + float64(style.width)/2
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">`, width, badgeLabel, status)
+	fmt.Fprintf(&b, `<title>%s: %s</title>`, badgeLabel, status)
+	b.WriteString(`<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`)
+	fmt.Fprintf(&b, `<clipPath id="r"><rect width="%d" height="20" rx="3" fill="#fff"/></clipPath>`, width)
+	fmt.Fprintf(&b, `<g clip-path="url(#r)"><rect width="%d" height="20" fill="#555"/><rect x="%d" width="%d" height="20" fill="%s"/><rect width="%d" height="20" fill="url(#s)"/></g>`,
+		badgeLabelWidth, badgeLabelWidth, style.width, style.color, width)
+	b.WriteString(`<g fill="#fff" text-anchor="middle" font-family="DejaVu Sans,Verdana,Geneva,sans-serif" font-size="11">`)
+	for _, t := range []struct {
+		x    float64
+		text string
+	}{{labelX, badgeLabel}, {statusX, string(status)}} {
+		fmt.Fprintf(&b, `<text x="%g" y="15" fill="#010101" fill-opacity=".3">%s</text><text x="%g" y="14">%s</text>`, t.x, t.text, t.x, t.text)
+	}
+	b.WriteString(`</g></svg>`)
+
+	return []byte(b.String())
+}
+
+const (
+	// DefaultBadgeTTL is how long BadgeHandler caches badges by default
+	DefaultBadgeTTL = time.Minute
+	// DefaultBadgeCacheSize is how many badges BadgeHandler caches at most by default
+	DefaultBadgeCacheSize = 1000
+)
+
+// BadgeHandler is an http.Handler serving the badges of the branches
+// of repositories at /{owner}/{repo}.svg?branch={branch}.
+// The default branch of the repository is used when no branch is provided.
+// While a build is running, the badge shows the status of the last finished build.
+//
+// Badges are cached for TTL, up to CacheSize of them. When Travis CI cannot be
+// reached, the last badge fetched is served, or an unknown badge if there is none.
+// Badges of repositories and branches which do not exist are not cached, and
+// concurrent requests for the same badge share a single fetch.
+type BadgeHandler struct {
+	// How long badges are cached, DefaultBadgeTTL when 0
+	TTL time.Duration
+	// How many badges are cached at most, DefaultBadgeCacheSize when 0.
+	// Expired badges are evicted first, then the least recently fetched ones.
+	CacheSize int
+
+	client *Client
+
+	mu    sync.Mutex
+	cache map[string]*cachedBadge
+	calls map[string]*badgeCall
+
+	// now returns the current time, replaced in tests
+	now func() time.Time
+}
+
+type cachedBadge struct {
+	svg       []byte
+	fetchedAt time.Time
+}
+
+// badgeCall is a fetch of a badge in progress, shared by concurrent requests
+type badgeCall struct {
+	done chan struct{}
+	svg  []byte
+}
+
+// NewBadgeHandler returns a BadgeHandler fetching branches with client
+func NewBadgeHandler(client *Client) *BadgeHandler {
+	return &BadgeHandler{
+		client: client,
+		cache:  map[string]*cachedBadge{},
+		calls:  map[string]*badgeCall{},
+		now:    time.Now,
+	}
+}
+
+// ServeHTTP serves the badge of the requested branch
+func (h *BadgeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	if !strings.HasSuffix(path, ".svg") {
+		http.NotFound(w, r)
+		return
+	}
+	parts := strings.Split(strings.TrimSuffix(path, ".svg"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	svg := h.badge(r, parts[0]+"/"+parts[1], r.URL.Query().Get("branch"))
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	// Badges change with every build, so they must not be cached by proxies
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.Write(svg)
+}
+
+// badge returns the cached badge of a branch, fetching it when it expired
+func (h *BadgeHandler) badge(r *http.Request, slug, branch string) []byte {
+	ttl := h.TTL
+	if ttl == 0 {
+		ttl = DefaultBadgeTTL
+	}
+
+	key := slug + "@" + branch
+
+	h.mu.Lock()
+	cached := h.cache[key]
+	if cached != nil && h.now().Sub(cached.fetchedAt) < ttl {
+		h.mu.Unlock()
+		return cached.svg
+	}
+	if call, ok := h.calls[key]; ok {
+		h.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.svg
+		case <-r.Context().Done():
+			if cached != nil {
+				return cached.svg
+			}
+			return RenderBadge(BadgeStatusUnknown)
+		}
+	}
+	call := &badgeCall{done: make(chan struct{})}
+	h.calls[key] = call
+	h.mu.Unlock()
+
+	status, found, err := h.fetch(r, slug, branch)
+	switch {
+	case err != nil && cached != nil:
+		call.svg = cached.svg
+	case err != nil:
+		call.svg = RenderBadge(BadgeStatusUnknown)
+	default:
+		call.svg = RenderBadge(status)
+	}
+
+	h.mu.Lock()
+	delete(h.calls, key)
+	if err == nil {
+		if found {
+			h.store(key, &cachedBadge{svg: call.svg, fetchedAt: h.now()}, ttl)
+		} else {
+			delete(h.cache, key)
+		}
+	}
+	h.mu.Unlock()
+	close(call.done)
+
+	return call.svg
+}
+
+// store caches a badge, evicting the expired badges, then the least
+// recently fetched ones, when the cache is full. h.mu must be held.
+func (h *BadgeHandler) store(key string, badge *cachedBadge, ttl time.Duration) {
+	size := h.CacheSize
+	if size == 0 {
+		size = DefaultBadgeCacheSize
+	}
+
+	if _, ok := h.cache[key]; !ok && len(h.cache) >= size {
+		now := h.now()
+		for k, b := range h.cache {
+			if now.Sub(b.fetchedAt) >= ttl {
+				delete(h.cache, k)
+			}
+		}
+		for len(h.cache) >= size {
+			oldest := ""
+			for k, b := range h.cache {
+				if oldest == "" || b.fetchedAt.Before(h.cache[oldest].fetchedAt) {
+					oldest = k
+				}
+			}
+			delete(h.cache, oldest)
+		}
+	}
+
+	h.cache[key] = badge
+}
+
+// fetch fetches the status of a branch. Repositories and branches
+// which do not exist are unknown and not found rather than an error.
+func (h *BadgeHandler) fetch(r *http.Request, slug, branch string) (BadgeStatus, bool, error) {
+	if branch == "" {
+		repo, resp, err := h.client.Repositories.Find(r.Context(), slug, nil)
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return BadgeStatusUnknown, false, nil
+		}
+		if err != nil {
+			return "", false, err
+		}
+		if repo.DefaultBranch == nil || repo.DefaultBranch.Name == nil {
+			return BadgeStatusUnknown, false, nil
+		}
+		branch = *repo.DefaultBranch.Name
+	}
+
+	b, resp, err := h.client.Branches.FindByRepoSlug(r.Context(), slug, url.PathEscape(branch), nil)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return BadgeStatusUnknown, false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	if b == nil || !buildRunning(b.LastBuild) {
+		return BranchBadgeStatus(b), true, nil
+	}
+
+	// Show the last finished build rather than the running one, like Travis CI
+	opt := &BuildsByRepoOption{
+		BranchName: []string{branch},
+		State:      finishedBuildStates,
+		Limit:      1,
+	}
+	builds, _, err := h.client.Builds.ListByRepoSlug(r.Context(), slug, opt)
+	if err != nil {
+		return "", false, err
+	}
+	if len(builds) == 0 {
+		return BadgeStatusUnknown, true, nil
+	}
+
+	return BuildBadgeStatus(builds[0]), true, nil
+}
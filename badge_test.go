@@ -0,0 +1,243 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBuildBadgeStatus(t *testing.T) {
+	cases := []struct {
+		build *Build
+		want  BadgeStatus
+	}{
+		{&Build{State: String(BuildStatePassed)}, BadgeStatusPassing},
+		{&Build{State: String(BuildStateFailed)}, BadgeStatusFailing},
+		{&Build{State: String(BuildStateErrored)}, BadgeStatusError},
+		{&Build{State: String(BuildStateCanceled)}, BadgeStatusCanceled},
+		{&Build{State: String(BuildStateStarted)}, BadgeStatusUnknown},
+		{&Build{}, BadgeStatusUnknown},
+		{nil, BadgeStatusUnknown},
+	}
+
+	for i, c := range cases {
+		if got := BuildBadgeStatus(c.build); got != c.want {
+			t.Errorf("#%d BuildBadgeStatus returned %q, want %q", i, got, c.want)
+		}
+	}
+
+	if got := BranchBadgeStatus(&Branch{LastBuild: &Build{State: String(BuildStatePassed)}}); got != BadgeStatusPassing {
+		t.Errorf("BranchBadgeStatus returned %q, want %q", got, BadgeStatusPassing)
+	}
+}
+
+func TestRenderBadge(t *testing.T) {
+	for status := range badgeStyles {
+		svg := RenderBadge(status)
+
+		if err := xml.Unmarshal(svg, new(interface{})); err != nil {
+			t.Errorf("RenderBadge(%q) returned invalid XML: %v", status, err)
+		}
+		if want := fmt.Sprintf(`aria-label="build: %s"`, status); !strings.Contains(string(svg), want) {
+			t.Errorf("RenderBadge(%q) returned %s, want it to contain %s", status, svg, want)
+		}
+	}
+
+	if !strings.Contains(string(RenderBadge(BadgeStatusPassing)), `fill="#4c1"`) {
+		t.Errorf("RenderBadge(%q) is not green", BadgeStatusPassing)
+	}
+	if got, want := string(RenderBadge("other")), string(RenderBadge(BadgeStatusUnknown)); got != want {
+		t.Errorf("RenderBadge returned %s, want %s", got, want)
+	}
+}
+
+func TestBadgeHandler_ServeHTTP(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	state, fetched := BuildStatePassed, 0
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/branch/master", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fetched++
+		fmt.Fprintf(w, `{"name":"master","last_build":{"state":%q}}`, state)
+	})
+	mux.HandleFunc(fmt.Sprintf("/repo/%s", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"slug":"shuheiktgw/go-travis-test","default_branch":{"name":"master"}}`)
+	})
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/branch/missing", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error_type":"not_found","error_message":"branch not found"}`)
+	})
+
+	now := time.Now()
+	h := NewBadgeHandler(client)
+	h.now = func() time.Time { return now }
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	cases := []struct {
+		path string
+		want BadgeStatus
+	}{
+		{"/shuheiktgw/go-travis-test.svg?branch=master", BadgeStatusPassing},
+		{"/shuheiktgw/go-travis-test.svg", BadgeStatusPassing},
+		{"/shuheiktgw/go-travis-test.svg?branch=missing", BadgeStatusUnknown},
+	}
+	for _, c := range cases {
+		w := get(c.path)
+		if w.Code != http.StatusOK {
+			t.Fatalf("BadgeHandler.ServeHTTP(%s) returned %d", c.path, w.Code)
+		}
+		if got := w.Header().Get("Content-Type"); got != "image/svg+xml" {
+			t.Errorf("BadgeHandler.ServeHTTP(%s) returned Content-Type %q", c.path, got)
+		}
+		if got, want := w.Body.String(), string(RenderBadge(c.want)); got != want {
+			t.Errorf("BadgeHandler.ServeHTTP(%s) returned %s, want %s", c.path, got, want)
+		}
+	}
+
+	// The badge is cached until it expires
+	state = BuildStateFailed
+	if got, want := get(cases[0].path).Body.String(), string(RenderBadge(BadgeStatusPassing)); got != want {
+		t.Errorf("BadgeHandler.ServeHTTP returned %s, want the cached %s", got, want)
+	}
+
+	now = now.Add(DefaultBadgeTTL)
+	if got, want := get(cases[0].path).Body.String(), string(RenderBadge(BadgeStatusFailing)); got != want {
+		t.Errorf("BadgeHandler.ServeHTTP returned %s, want %s", got, want)
+	}
+
+	if fetched != 3 {
+		t.Errorf("BadgeHandler fetched the branch %d times, want 3", fetched)
+	}
+
+	for _, path := range []string{"/shuheiktgw.svg", "/shuheiktgw/go-travis-test", "/a/b/c.svg"} {
+		if w := get(path); w.Code != http.StatusNotFound {
+			t.Errorf("BadgeHandler.ServeHTTP(%s) returned %d, want %d", path, w.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestBadgeHandler_ServeHTTP_running(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/branch/master", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"name":"master","last_build":{"state":"started"}}`)
+	})
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/builds", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		testFormValues(t, r, values{"branch.name": "master", "state": "passed,failed,errored,canceled", "limit": "1"})
+		fmt.Fprint(w, `{"builds":[{"state":"failed"}]}`)
+	})
+
+	w := httptest.NewRecorder()
+	NewBadgeHandler(client).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/shuheiktgw/go-travis-test.svg?branch=master", nil))
+
+	if got, want := w.Body.String(), string(RenderBadge(BadgeStatusFailing)); got != want {
+		t.Errorf("BadgeHandler.ServeHTTP returned %s, want %s", got, want)
+	}
+}
+
+func TestBadgeHandler_cache(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/branch/", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, fmt.Sprintf("/repo/%s/branch/", testRepoSlug))
+		if name == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error_type":"not_found","error_message":"branch not found"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"name":%q,"last_build":{"state":"passed"}}`, name)
+	})
+
+	now := time.Now()
+	h := NewBadgeHandler(client)
+	h.CacheSize = 2
+	h.now = func() time.Time { return now }
+
+	for _, branch := range []string{"master", "develop", "missing", "feature"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/shuheiktgw/go-travis-test.svg?branch="+branch, nil))
+		now = now.Add(time.Second)
+	}
+
+	var keys []string
+	for k := range h.cache {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	want := []string{testRepoSlug + "@develop", testRepoSlug + "@feature"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("BadgeHandler cached %v, want %v", keys, want)
+	}
+}
+
+func TestBadgeHandler_concurrentFetch(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var fetched int32
+	started, release := make(chan struct{}), make(chan struct{})
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/branch/master", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&fetched, 1) == 1 {
+			close(started)
+		}
+		<-release
+		fmt.Fprint(w, `{"name":"master","last_build":{"state":"passed"}}`)
+	})
+
+	h := NewBadgeHandler(client)
+
+	var wg sync.WaitGroup
+	bodies := make(chan string, 3)
+	get := func() {
+		defer wg.Done()
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/shuheiktgw/go-travis-test.svg?branch=master", nil))
+		bodies <- w.Body.String()
+	}
+
+	wg.Add(1)
+	go get()
+	<-started
+
+	wg.Add(2)
+	go get()
+	go get()
+	// Let the other requests wait for the fetch in progress
+	time.Sleep(10 * time.Millisecond)
+
+	close(release)
+	wg.Wait()
+	close(bodies)
+
+	for body := range bodies {
+		if want := string(RenderBadge(BadgeStatusPassing)); body != want {
+			t.Errorf("BadgeHandler.ServeHTTP returned %s, want %s", body, want)
+		}
+	}
+
+	if fetched != 1 {
+		t.Errorf("BadgeHandler fetched the branch %d times, want 1", fetched)
+	}
+}
@@ -19,11 +19,11 @@ func TestBetaMigrationRequestsService_List(t *testing.T) {
 
 	mux.HandleFunc(fmt.Sprintf("/user/%d/beta_migration_requests", testUserId), func(w http.ResponseWriter, r *http.Request) {
 		testMethod(t, r, http.MethodGet)
-		testFormValues(t, r, values{"include": "beta_migration_requests.organizations"})
+		testFormValues(t, r, values{"include": "beta_migration_request.organizations"})
 		fmt.Fprint(w, `{"beta_migration_requests":[{"id":1,"owner_id":2,"owner_name":"test","owner_type":"User"}]}`)
 	})
 
-	opt := BetaMigrationRequestsOption{Include: []string{"beta_migration_requests.organizations"}}
+	opt := BetaMigrationRequestsOption{Include: []string{"beta_migration_request.organizations"}}
 	requests, _, err := client.BetaMigrationRequests.List(context.Background(), testUserId, &opt)
 
 	if err != nil {
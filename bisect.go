@@ -0,0 +1,195 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrBranchNotFailing is returned by BisectBranch when
+// the last conclusive build of the branch passed
+var ErrBranchNotFailing = errors.New("travis: the branch is not failing")
+
+// ErrBisectLimitReached is returned by BisectBranch when opt.MaxBuilds
+// builds were scanned before the last passing build was found
+var ErrBisectLimitReached = errors.New("travis: no passing build found within the builds scanned")
+
+// BisectOption specifies the optional parameters of BisectBranch
+type BisectOption struct {
+	// The shas of commits between the last passing and the first failing build,
+	// e.g. listed from the compare URL, which are built when Retrigger is set
+	// unless they were already. The API cannot list the commits of a branch.
+	Shas []string
+	// Whether to trigger builds of the untested commits
+	Retrigger bool
+	// How many recent builds of the branch to scan at most. Defaults to 1000.
+	MaxBuilds int
+}
+
+// Bisection is the result of BisectBranch
+type Bisection struct {
+	RepoSlug string
+	Branch   string
+	// The most recent passing build before the failure, nil if none passed
+	LastPassing *Build
+	// The oldest failing build after LastPassing
+	FirstFailing *Build
+	// The URL comparing the commits of LastPassing and FirstFailing
+	CompareUrl string
+	// The commits built after LastPassing up to FirstFailing, oldest first
+	Commits []*Commit
+	// The shas between LastPassing and FirstFailing without a passing or
+	// failing build, e.g. because it errored or was canceled, oldest first
+	Untested []string
+	// The requests triggered to build Untested, when Retrigger is set
+	Triggered []*Request
+}
+
+// BisectBranch walks the history of a failing branch to find its last passing
+// and first failing builds, and the commits between them. Builds which errored
+// or were canceled are inconclusive, and their commits are reported as untested.
+// With Retrigger, builds of the untested commits are requested to narrow down
+// the commit which broke the branch.
+//
+// Builds are scanned page by page, most recent first, until the last passing
+// build or opt.MaxBuilds were scanned, i.e. up to one request per 100 builds.
+// It returns ErrBisectLimitReached if the limit is hit first.
+//
+// Pull request builds are ignored, as their branch is the branch they target.
+func (c *Client) BisectBranch(ctx context.Context, repoSlug string, branch string, opt *BisectOption) (*Bisection, *http.Response, error) {
+	if opt == nil {
+		opt = &BisectOption{}
+	}
+
+	maxBuilds, limit := (&BuildSearchOption{MaxBuilds: opt.MaxBuilds}).maxBuilds()
+	listOpt := BuildsByRepoOption{
+		BranchName: []string{branch},
+		EventType:  []string{BuildEventTypePush, BuildEventTypeApi, BuildEventTypeCron},
+		SortBy:     SortOrder{SortDesc(BuildSortFieldId)},
+		Limit:      limit,
+	}
+
+	bisection := &Bisection{RepoSlug: repoSlug, Branch: branch}
+	var since []*Build
+	passing, limited := false, false
+	scanned := 0
+
+	resp, err := c.Builds.walkByRepoSlug(ctx, repoSlug, listOpt, func(b *Build) bool {
+		scanned++
+		limited = scanned >= maxBuilds
+		if b.State == nil || !buildStateFinished(*b.State) {
+			return !limited
+		}
+
+		switch *b.State {
+		case BuildStatePassed:
+			if bisection.FirstFailing == nil {
+				passing = true
+			} else {
+				bisection.LastPassing = b
+			}
+			return false
+		case BuildStateFailed:
+			bisection.FirstFailing = b
+			since = append(since, b)
+		default:
+			if bisection.FirstFailing != nil {
+				since = append(since, b)
+			}
+		}
+		return !limited
+	})
+	if err != nil {
+		return nil, resp, err
+	}
+	if limited && !passing && bisection.LastPassing == nil {
+		return nil, resp, ErrBisectLimitReached
+	}
+	if passing || bisection.FirstFailing == nil {
+		return nil, resp, ErrBranchNotFailing
+	}
+
+	// since lists the builds from the most recent failing one back to the
+	// last passing one, only the builds up to the first failing one matter
+	for i := len(since) - 1; i >= 0; i-- {
+		b := since[i]
+		if b.Commit != nil {
+			bisection.Commits = append(bisection.Commits, b.Commit)
+		}
+		if b == bisection.FirstFailing {
+			break
+		}
+	}
+
+	tested := map[string]bool{}
+	if bisection.LastPassing != nil && bisection.LastPassing.Commit != nil && bisection.LastPassing.Commit.Sha != nil {
+		tested[*bisection.LastPassing.Commit.Sha] = true
+	}
+	for _, b := range since {
+		if b.Commit != nil && b.Commit.Sha != nil && (*b.State == BuildStatePassed || *b.State == BuildStateFailed) {
+			tested[*b.Commit.Sha] = true
+		}
+	}
+
+	untested := map[string]bool{}
+	addUntested := func(sha string) {
+		if sha != "" && !tested[sha] && !untested[sha] {
+			untested[sha] = true
+			bisection.Untested = append(bisection.Untested, sha)
+		}
+	}
+	for _, commit := range bisection.Commits {
+		if commit.Sha != nil {
+			addUntested(*commit.Sha)
+		}
+	}
+	for _, sha := range opt.Shas {
+		addUntested(sha)
+	}
+
+	bisection.CompareUrl = bisectCompareUrl(bisection.LastPassing, bisection.FirstFailing)
+
+	if opt.Retrigger {
+		for _, sha := range bisection.Untested {
+			body := &RequestBody{
+				Branch:  branch,
+				Sha:     sha,
+				Message: fmt.Sprintf("Bisecting the first failing build of %s", branch),
+			}
+			var request *Request
+			request, resp, err = c.Requests.CreateByRepoSlug(ctx, repoSlug, body)
+			if err != nil {
+				return bisection, resp, err
+			}
+			bisection.Triggered = append(bisection.Triggered, request)
+		}
+	}
+
+	return bisection, resp, nil
+}
+
+// bisectCompareUrl returns the URL comparing the commits of two builds, built
+// from the compare URL of the failing build which compares the commits it was pushed with
+func bisectCompareUrl(passing, failing *Build) string {
+	if failing.Commit == nil || failing.Commit.CompareUrl == nil {
+		return ""
+	}
+	compareUrl := *failing.Commit.CompareUrl
+
+	if passing == nil || passing.Commit == nil || passing.Commit.Sha == nil || failing.Commit.Sha == nil {
+		return compareUrl
+	}
+
+	i := strings.Index(compareUrl, "/compare/")
+	if i < 0 {
+		return compareUrl
+	}
+	return fmt.Sprintf("%s/compare/%s...%s", compareUrl[:i], *passing.Commit.Sha, *failing.Commit.Sha)
+}
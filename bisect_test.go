@@ -0,0 +1,104 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestClient_BisectBranch(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/builds", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		testFormValues(t, r, values{"branch.name": "master", "event_type": "push,api,cron", "sort_by": "id:desc", "limit": "100"})
+		fmt.Fprint(w, `{"builds":[
+		  {"id":10,"state":"started","commit":{"sha":"h10"}},
+		  {"id":9,"state":"errored","commit":{"sha":"h9"}},
+		  {"id":8,"state":"failed","commit":{"sha":"h8"}},
+		  {"id":7,"state":"failed","commit":{"sha":"h7","compare_url":"https://github.com/shuheiktgw/go-travis-test/compare/h6...h7"}},
+		  {"id":6,"state":"canceled","commit":{"sha":"h6"}},
+		  {"id":5,"state":"passed","commit":{"sha":"h5"}},
+		  {"id":4,"state":"failed","commit":{"sha":"h4"}}
+		]}`)
+	})
+
+	var triggered []string
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/requests", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPost)
+		sha := "h6"
+		if len(triggered) > 0 {
+			sha = "hx"
+		}
+		testBody(t, r, fmt.Sprintf(`{"message":"Bisecting the first failing build of master","branch":"master","sha":"%s"}`+"\n", sha))
+		triggered = append(triggered, sha)
+		fmt.Fprintf(w, `{"request":{"id":%d}}`, len(triggered))
+	})
+
+	opt := &BisectOption{Shas: []string{"h6", "hx", "h7"}, Retrigger: true}
+	bisection, _, err := client.BisectBranch(context.Background(), testRepoSlug, "master", opt)
+
+	if err != nil {
+		t.Fatalf("Client.BisectBranch returned error: %v", err)
+	}
+
+	if *bisection.LastPassing.Id != 5 || *bisection.FirstFailing.Id != 7 {
+		t.Errorf("Client.BisectBranch returned last passing %d and first failing %d, want 5 and 7", *bisection.LastPassing.Id, *bisection.FirstFailing.Id)
+	}
+
+	var shas []string
+	for _, c := range bisection.Commits {
+		shas = append(shas, *c.Sha)
+	}
+	if want := []string{"h6", "h7"}; !reflect.DeepEqual(shas, want) {
+		t.Errorf("Client.BisectBranch returned commits %v, want %v", shas, want)
+	}
+
+	if want := []string{"h6", "hx"}; !reflect.DeepEqual(bisection.Untested, want) {
+		t.Errorf("Client.BisectBranch returned untested %v, want %v", bisection.Untested, want)
+	}
+
+	if want := "https://github.com/shuheiktgw/go-travis-test/compare/h5...h7"; bisection.CompareUrl != want {
+		t.Errorf("Client.BisectBranch returned compare URL %q, want %q", bisection.CompareUrl, want)
+	}
+
+	if len(bisection.Triggered) != 2 || len(triggered) != 2 {
+		t.Errorf("Client.BisectBranch triggered %v", triggered)
+	}
+}
+
+func TestClient_BisectBranch_notFailing(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/builds", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"builds":[{"id":3,"state":"canceled"},{"id":2,"state":"passed"},{"id":1,"state":"failed"}]}`)
+	})
+
+	if _, _, err := client.BisectBranch(context.Background(), testRepoSlug, "master", nil); err != ErrBranchNotFailing {
+		t.Errorf("Client.BisectBranch returned %v, want %v", err, ErrBranchNotFailing)
+	}
+}
+
+func TestClient_BisectBranch_limitReached(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/builds", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		testFormValues(t, r, values{"branch.name": "master", "event_type": "push,api,cron", "sort_by": "id:desc", "limit": "3"})
+		fmt.Fprint(w, `{"builds":[{"id":5,"state":"failed"},{"id":4,"state":"errored"},{"id":3,"state":"failed"}]}`)
+	})
+
+	// The passing build is beyond MaxBuilds
+	if _, _, err := client.BisectBranch(context.Background(), testRepoSlug, "master", &BisectOption{MaxBuilds: 3}); err != ErrBisectLimitReached {
+		t.Errorf("Client.BisectBranch returned %v, want %v", err, ErrBisectLimitReached)
+	}
+}
@@ -45,8 +45,8 @@ type BranchesOption struct {
 	Limit int `url:"limit,omitempty"`
 	// How many branches to skip before the first entry in the response
 	Offset int `url:"offset,omitempty"`
-	// Attributes to sort branches by
-	SortBy string `url:"sort_by,omitempty"`
+	// Attributes to sort branches by, e.g. SortOrder{SortAsc(BranchSortFieldName)}
+	SortBy SortOrder `url:"sort_by,omitempty"`
 	// List of attributes to eager load
 	Include []string `url:"include,omitempty,comma"`
 }
@@ -70,7 +70,7 @@ func TestBranchesService_Integration_ListByRepoId(t *testing.T) {
 	cases := []*BranchesOption{
 		{},
 		{Limit: 1},
-		{SortBy: "id"},
+		{SortBy: SortOrder{SortAsc(BranchSortFieldName)}},
 		{Offset: 0},
 		{Include: []string{"branch.recent_builds"}},
 	}
@@ -98,7 +98,7 @@ func TestBranchesService_Integration_ListByRepoSlug(t *testing.T) {
 	cases := []*BranchesOption{
 		{},
 		{Limit: 1},
-		{SortBy: "id"},
+		{SortBy: SortOrder{SortAsc(BranchSortFieldName)}},
 		{Offset: 0},
 		{Include: []string{"branch.repository"}},
 	}
@@ -31,6 +31,7 @@ type Broadcast struct {
 	// When the broadcast was created
 	CreatedAt *string `json:"created_at,omitempty"`
 	// Either a user, organization or repository, or null for global
+	// Use RecipientResource to decode it into its Go type
 	Recipient interface{} `json:"recipient,omitempty"`
 	*Metadata
 }
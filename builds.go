@@ -7,9 +7,11 @@ package travis
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 )
 
 // BuildsService handles communication with the builds
@@ -69,8 +71,8 @@ type BuildsOption struct {
 	Limit int `url:"limit,omitempty"`
 	// How many builds to skip before the first entry in the response
 	Offset int `url:"offset,omitempty"`
-	// Attributes to sort builds by
-	SortBy string `url:"sort_by,omitempty"`
+	// Attributes to sort builds by, e.g. SortOrder{SortDesc(BuildSortFieldStartedAt)}
+	SortBy SortOrder `url:"sort_by,omitempty"`
 	// List of attributes to eager load
 	Include []string `url:"include,omitempty,comma"`
 }
@@ -91,8 +93,8 @@ type BuildsByRepoOption struct {
 	Limit int `url:"limit,omitempty"`
 	// How many builds to skip before the first entry in the response
 	Offset int `url:"offset,omitempty"`
-	// Attributes to sort builds by
-	SortBy string `url:"sort_by,omitempty"`
+	// Attributes to sort builds by, e.g. SortOrder{SortDesc(BuildSortFieldStartedAt)}
+	SortBy SortOrder `url:"sort_by,omitempty"`
 	// List of attributes to eager load
 	Include []string `url:"include,omitempty,comma"`
 }
@@ -129,11 +131,36 @@ const (
 	BuildStateCanceled = "canceled"
 )
 
+// finishedBuildStates are the states of the builds which are not running
+var finishedBuildStates = []string{BuildStatePassed, BuildStateFailed, BuildStateErrored, BuildStateCanceled}
+
+// buildStateFinished tells if a build in the given state is not running
+func buildStateFinished(state string) bool {
+	for _, s := range finishedBuildStates {
+		if state == s {
+			return true
+		}
+	}
+	return false
+}
+
+// buildRunning tells if a build has not finished yet
+func buildRunning(b *Build) bool {
+	if b == nil || b.State == nil {
+		return false
+	}
+	return !buildStateFinished(*b.State)
+}
+
 const (
 	// BuildEventTypePush represents the build event type `push`
 	BuildEventTypePush = "push"
 	// BuildEventTypePullRequest represents the build event type `pull_request`
 	BuildEventTypePullRequest = "pull_request"
+	// BuildEventTypeApi represents the build event type `api`
+	BuildEventTypeApi = "api"
+	// BuildEventTypeCron represents the build event type `cron`
+	BuildEventTypeCron = "cron"
 )
 
 // Find fetches a build based on the provided build id
@@ -273,3 +300,114 @@ func (bs *BuildsService) Restart(ctx context.Context, id uint) (*Build, *http.Re
 
 	return br.Build, resp, err
 }
+
+// ErrBuildNotFound is returned when no build matches the criteria of a search
+var ErrBuildNotFound = errors.New("travis: build not found")
+
+// BuildSearchOption specifies the optional parameters of FindByCommit and ListByPullRequest
+type BuildSearchOption struct {
+	// How many recent builds to scan at most. Defaults to 1000.
+	MaxBuilds int
+}
+
+// maxBuilds returns how many builds to scan, and the page size to scan them with
+func (o *BuildSearchOption) maxBuilds() (int, int) {
+	maxBuilds := 1000
+	if o != nil && o.MaxBuilds > 0 {
+		maxBuilds = o.MaxBuilds
+	}
+	if maxBuilds < 100 {
+		return maxBuilds, maxBuilds
+	}
+	return maxBuilds, 0
+}
+
+// FindByCommit fetches the latest build of given repository slug for a commit.
+// sha may be abbreviated. The API cannot filter builds by commit, so builds
+// are scanned page by page, most recent first, until one matches or
+// opt.MaxBuilds were scanned, i.e. up to one request per 100 builds.
+// It returns ErrBuildNotFound if no build matches.
+func (bs *BuildsService) FindByCommit(ctx context.Context, repoSlug string, sha string, opt *BuildSearchOption) (*Build, *http.Response, error) {
+	sha = strings.ToLower(sha)
+	if sha == "" {
+		return nil, nil, errors.New("travis: sha must not be empty")
+	}
+
+	maxBuilds, limit := opt.maxBuilds()
+	listOpt := BuildsByRepoOption{SortBy: SortOrder{SortDesc(BuildSortFieldId)}, Limit: limit}
+
+	var found *Build
+	scanned := 0
+	resp, err := bs.walkByRepoSlug(ctx, repoSlug, listOpt, func(b *Build) bool {
+		scanned++
+		if b.Commit != nil && b.Commit.Sha != nil && strings.HasPrefix(strings.ToLower(*b.Commit.Sha), sha) {
+			found = b
+			return false
+		}
+		return scanned < maxBuilds
+	})
+	if err != nil {
+		return nil, resp, err
+	}
+	if found == nil {
+		return nil, resp, ErrBuildNotFound
+	}
+
+	return found, resp, nil
+}
+
+// ListByPullRequest fetches the builds of given repository slug for a pull request,
+// the latest first. The API cannot filter builds by pull request number, so the
+// builds triggered by pull requests are filtered by the API, then scanned page by
+// page until opt.MaxBuilds were scanned, i.e. up to one request per 100 builds.
+// A pull request may be built again long after it was opened, so builds older than
+// the ones scanned are missed; raise opt.MaxBuilds to find them.
+func (bs *BuildsService) ListByPullRequest(ctx context.Context, repoSlug string, prNumber uint, opt *BuildSearchOption) ([]*Build, *http.Response, error) {
+	maxBuilds, limit := opt.maxBuilds()
+	listOpt := BuildsByRepoOption{
+		EventType: []string{BuildEventTypePullRequest},
+		SortBy:    SortOrder{SortDesc(BuildSortFieldId)},
+		Limit:     limit,
+	}
+
+	var builds []*Build
+	scanned := 0
+	resp, err := bs.walkByRepoSlug(ctx, repoSlug, listOpt, func(b *Build) bool {
+		scanned++
+		if b.PullRequestNumber != nil && *b.PullRequestNumber == prNumber {
+			builds = append(builds, b)
+		}
+		return scanned < maxBuilds
+	})
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return builds, resp, nil
+}
+
+// walkByRepoSlug calls fn with the builds of given repository slug matching opt,
+// fetching them page by page, until fn returns false or there are no more builds.
+// The page size is opt.Limit, 100 by default.
+func (bs *BuildsService) walkByRepoSlug(ctx context.Context, repoSlug string, opt BuildsByRepoOption, fn func(*Build) bool) (*http.Response, error) {
+	if opt.Limit == 0 {
+		opt.Limit = 100
+	}
+
+	for {
+		builds, resp, err := bs.ListByRepoSlug(ctx, repoSlug, &opt)
+		if err != nil {
+			return resp, err
+		}
+
+		for _, b := range builds {
+			if !fn(b) {
+				return resp, nil
+			}
+		}
+		if len(builds) < opt.Limit {
+			return resp, nil
+		}
+		opt.Offset += opt.Limit
+	}
+}
@@ -34,7 +34,7 @@ func TestBuildsService_Integration_List(t *testing.T) {
 	cases := []*BuildsOption{
 		{},
 		{Limit: 1},
-		{SortBy: "id"},
+		{SortBy: SortOrder{SortAsc(BuildSortFieldId)}},
 		{Offset: 0},
 		{Include: []string{"build.branch", "build.commit"}},
 	}
@@ -62,7 +62,7 @@ func TestBuildsService_Integration_ListByRepoId(t *testing.T) {
 	cases := []*BuildsByRepoOption{
 		{},
 		{Limit: 1},
-		{SortBy: "id"},
+		{SortBy: SortOrder{SortAsc(BuildSortFieldId)}},
 		{Offset: 0},
 		{State: []string{BuildStateCanceled}},
 		{PreviousState: []string{BuildStatePassed}},
@@ -95,7 +95,7 @@ func TestBuildsService_Integration_ListByRepoSlug(t *testing.T) {
 	cases := []*BuildsByRepoOption{
 		{},
 		{Limit: 1},
-		{SortBy: "id"},
+		{SortBy: SortOrder{SortAsc(BuildSortFieldId)}},
 		{Offset: 0},
 		{State: []string{BuildStateCanceled}},
 		{PreviousState: []string{BuildStatePassed}},
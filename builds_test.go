@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"net/http"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -47,7 +48,7 @@ func TestBuildsService_List(t *testing.T) {
 		fmt.Fprint(w, `{"builds": [{"id":1,"number":"1","state":"created","duration":10}]}`)
 	})
 
-	builds, _, err := client.Builds.List(context.Background(), &BuildsOption{Limit: 50, SortBy: "id", Include: []string{"build.commit", "build.branch"}})
+	builds, _, err := client.Builds.List(context.Background(), &BuildsOption{Limit: 50, SortBy: SortOrder{SortAsc(BuildSortFieldId)}, Include: []string{"build.commit", "build.branch"}})
 
 	if err != nil {
 		t.Errorf("Builds.Find returned error: %v", err)
@@ -69,7 +70,7 @@ func TestBuildsService_ListByRepoId(t *testing.T) {
 		fmt.Fprint(w, `{"builds": [{"id":1,"number":"1","state":"created","duration":10}]}`)
 	})
 
-	builds, _, err := client.Builds.ListByRepoId(context.Background(), testRepoId, &BuildsByRepoOption{Limit: 50, SortBy: "id"})
+	builds, _, err := client.Builds.ListByRepoId(context.Background(), testRepoId, &BuildsByRepoOption{Limit: 50, SortBy: SortOrder{SortAsc(BuildSortFieldId)}})
 
 	if err != nil {
 		t.Errorf("Builds.FindByRepoId returned error: %v", err)
@@ -91,7 +92,7 @@ func TestBuildsService_ListByRepoSlug(t *testing.T) {
 		fmt.Fprint(w, `{"builds": [{"id":1,"number":"1","state":"created","duration":10}]}`)
 	})
 
-	builds, _, err := client.Builds.ListByRepoSlug(context.Background(), testRepoSlug, &BuildsByRepoOption{Limit: 50, SortBy: "id"})
+	builds, _, err := client.Builds.ListByRepoSlug(context.Background(), testRepoSlug, &BuildsByRepoOption{Limit: 50, SortBy: SortOrder{SortAsc(BuildSortFieldId)}})
 
 	if err != nil {
 		t.Errorf("Builds.FindByRepoSlug returned error: %v", err)
@@ -144,3 +145,110 @@ func TestBuildsService_Restart(t *testing.T) {
 		t.Errorf("Build.Restart returned %+v, want %+v", build, want)
 	}
 }
+
+func TestBuildsService_walkByRepoSlug(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/builds", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		switch r.FormValue("offset") {
+		case "":
+			testFormValues(t, r, values{"limit": "2"})
+			fmt.Fprint(w, `{"builds":[{"id":5},{"id":4}]}`)
+		case "2":
+			testFormValues(t, r, values{"limit": "2", "offset": "2"})
+			fmt.Fprint(w, `{"builds":[{"id":3},{"id":2}]}`)
+		default:
+			t.Errorf("unexpected offset %s", r.FormValue("offset"))
+		}
+	})
+
+	var ids []uint
+	_, err := client.Builds.walkByRepoSlug(context.Background(), testRepoSlug, BuildsByRepoOption{Limit: 2}, func(b *Build) bool {
+		ids = append(ids, *b.Id)
+		return *b.Id > 3
+	})
+
+	if err != nil {
+		t.Errorf("Builds.walkByRepoSlug returned error: %v", err)
+	}
+
+	if want := []uint{5, 4, 3}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("Builds.walkByRepoSlug walked %v, want %v", ids, want)
+	}
+}
+
+func TestBuildsService_FindByCommit(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	pages := 0
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/builds", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		pages++
+		switch r.FormValue("offset") {
+		case "":
+			testFormValues(t, r, values{"sort_by": "id:desc", "limit": "100"})
+			fmt.Fprint(w, `{"builds":[`+strings.Repeat(`{"id":3,"commit":{"sha":"ccc"}},`, 99)+`{"id":2,"commit":{"sha":"62aae5f70ceee39123ef"}}]}`)
+		case "100":
+			fmt.Fprint(w, `{"builds":[{"id":1,"commit":{"sha":"62aae5f70ceee39123ef"}}]}`)
+		default:
+			t.Errorf("unexpected offset %s", r.FormValue("offset"))
+		}
+	})
+
+	build, _, err := client.Builds.FindByCommit(context.Background(), testRepoSlug, "62AAE5F", nil)
+
+	if err != nil {
+		t.Fatalf("Builds.FindByCommit returned error: %v", err)
+	}
+
+	want := &Build{Id: Uint(2), Commit: &Commit{Sha: String("62aae5f70ceee39123ef")}}
+	if !reflect.DeepEqual(build, want) {
+		t.Errorf("Builds.FindByCommit returned %+v, want %+v", build, want)
+	}
+	if pages != 1 {
+		t.Errorf("Builds.FindByCommit fetched %d pages, want 1", pages)
+	}
+
+	if _, _, err := client.Builds.FindByCommit(context.Background(), testRepoSlug, "ddd", nil); err != ErrBuildNotFound {
+		t.Errorf("Builds.FindByCommit returned %v, want %v", err, ErrBuildNotFound)
+	}
+
+	// The second page is beyond MaxBuilds
+	pages = 0
+	if _, _, err := client.Builds.FindByCommit(context.Background(), testRepoSlug, "ddd", &BuildSearchOption{MaxBuilds: 100}); err != ErrBuildNotFound {
+		t.Errorf("Builds.FindByCommit returned %v, want %v", err, ErrBuildNotFound)
+	}
+	if pages != 1 {
+		t.Errorf("Builds.FindByCommit fetched %d pages, want 1", pages)
+	}
+}
+
+func TestBuildsService_ListByPullRequest(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/builds", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		testFormValues(t, r, values{"event_type": "pull_request", "sort_by": "id:desc", "limit": "100"})
+		fmt.Fprint(w, `{"builds":[
+		  {"id":4,"pull_request_number":2},
+		  {"id":3,"pull_request_number":1},
+		  {"id":2,"pull_request_number":2},
+		  {"id":1,"pull_request_number":1}
+		]}`)
+	})
+
+	builds, _, err := client.Builds.ListByPullRequest(context.Background(), testRepoSlug, 1, nil)
+
+	if err != nil {
+		t.Fatalf("Builds.ListByPullRequest returned error: %v", err)
+	}
+
+	want := []*Build{{Id: Uint(3), PullRequestNumber: Uint(1)}, {Id: Uint(1), PullRequestNumber: Uint(1)}}
+	if !reflect.DeepEqual(builds, want) {
+		t.Errorf("Builds.ListByPullRequest returned %+v, want %+v", builds, want)
+	}
+}
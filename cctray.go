@@ -0,0 +1,271 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CCTrayProjects is a CCTray feed, the cc.xml format read by build monitors
+//
+// CCTray docs: https://cctray.org/v1/
+type CCTrayProjects struct {
+	XMLName  xml.Name         `xml:"Projects"`
+	Projects []*CCTrayProject `xml:"Project"`
+}
+
+// CCTrayProject is a project of a CCTray feed
+type CCTrayProject struct {
+	// The name of the project, the repository slug followed by the branch
+	Name string `xml:"name,attr"`
+	// Sleeping, or Building when a build is running
+	Activity string `xml:"activity,attr"`
+	// The status of the last finished build: Success, Failure, Exception or Unknown
+	LastBuildStatus string `xml:"lastBuildStatus,attr"`
+	// The number of the last finished build
+	LastBuildLabel string `xml:"lastBuildLabel,attr,omitempty"`
+	// When the last finished build finished
+	LastBuildTime string `xml:"lastBuildTime,attr,omitempty"`
+	// The URL of the last build on Travis CI
+	WebUrl string `xml:"webUrl,attr"`
+}
+
+// Activities and statuses of CCTray projects
+const (
+	CCTrayActivitySleeping = "Sleeping"
+	CCTrayActivityBuilding = "Building"
+
+	CCTrayStatusSuccess   = "Success"
+	CCTrayStatusFailure   = "Failure"
+	CCTrayStatusException = "Exception"
+	CCTrayStatusUnknown   = "Unknown"
+)
+
+// CCTrayTarget is a branch of a repository listed in a CCTray feed
+type CCTrayTarget struct {
+	RepoSlug string
+	// The branch, the default branch of the repository when empty
+	Branch string
+}
+
+// cctrayStatus maps the state of a build to a CCTray status
+func cctrayStatus(state string) string {
+	switch state {
+	case BuildStatePassed:
+		return CCTrayStatusSuccess
+	case BuildStateFailed:
+		return CCTrayStatusFailure
+	case BuildStateErrored, BuildStateCanceled:
+		return CCTrayStatusException
+	default:
+		return CCTrayStatusUnknown
+	}
+}
+
+// NewCCTrayProject returns the CCTray project of a branch of a repository,
+// given its last build and its last finished build, which are the same
+// when no build is running. webURL is the URL of the Travis CI web interface,
+// e.g. https://travis-ci.com/
+func NewCCTrayProject(repoSlug, branch string, last, lastFinished *Build, webURL string) *CCTrayProject {
+	p := &CCTrayProject{
+		Name:            repoSlug,
+		Activity:        CCTrayActivitySleeping,
+		LastBuildStatus: CCTrayStatusUnknown,
+		WebUrl:          strings.TrimSuffix(webURL, "/") + "/" + repoSlug,
+	}
+	if branch != "" {
+		p.Name = fmt.Sprintf("%s (%s)", repoSlug, branch)
+	}
+
+	if buildRunning(last) {
+		p.Activity = CCTrayActivityBuilding
+	}
+	if last != nil && last.Id != nil {
+		p.WebUrl = fmt.Sprintf("%s/builds/%d", p.WebUrl, *last.Id)
+	}
+
+	if lastFinished != nil && !buildRunning(lastFinished) {
+		if lastFinished.State != nil {
+			p.LastBuildStatus = cctrayStatus(*lastFinished.State)
+		}
+		if lastFinished.Number != nil {
+			p.LastBuildLabel = *lastFinished.Number
+		}
+		if lastFinished.FinishedAt != nil {
+			p.LastBuildTime = *lastFinished.FinishedAt
+		}
+	}
+
+	return p
+}
+
+// CCTray builds a CCTray feed of the provided branches.
+// Repositories and branches which do not exist are listed as unknown projects.
+func (c *Client) CCTray(ctx context.Context, targets []*CCTrayTarget) (*CCTrayProjects, *http.Response, error) {
+	feed := &CCTrayProjects{}
+	var resp *http.Response
+	var err error
+
+	for _, t := range targets {
+		branch := t.Branch
+		if branch == "" {
+			var repo *Repository
+			repo, resp, err = c.Repositories.Find(ctx, t.RepoSlug, nil)
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				feed.Projects = append(feed.Projects, NewCCTrayProject(t.RepoSlug, "", nil, nil, c.webURL()))
+				continue
+			}
+			if err != nil {
+				return nil, resp, err
+			}
+			if repo.DefaultBranch != nil && repo.DefaultBranch.Name != nil {
+				branch = *repo.DefaultBranch.Name
+			}
+		}
+
+		var b *Branch
+		b, resp, err = c.Branches.FindByRepoSlug(ctx, t.RepoSlug, url.PathEscape(branch), nil)
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			feed.Projects = append(feed.Projects, NewCCTrayProject(t.RepoSlug, branch, nil, nil, c.webURL()))
+			continue
+		}
+		if err != nil {
+			return nil, resp, err
+		}
+
+		last, lastFinished := b.LastBuild, b.LastBuild
+		if buildRunning(last) {
+			opt := &BuildsByRepoOption{
+				BranchName: []string{branch},
+				State:      finishedBuildStates,
+				Limit:      1,
+			}
+			var builds []*Build
+			builds, resp, err = c.Builds.ListByRepoSlug(ctx, t.RepoSlug, opt)
+			if err != nil {
+				return nil, resp, err
+			}
+			lastFinished = nil
+			if len(builds) > 0 {
+				lastFinished = builds[0]
+			}
+		}
+
+		feed.Projects = append(feed.Projects, NewCCTrayProject(t.RepoSlug, branch, last, lastFinished, c.webURL()))
+	}
+
+	return feed, resp, nil
+}
+
+// webURL returns the URL of the web interface matching the API of the client,
+// e.g. https://travis-ci.com/ for https://api.travis-ci.com/
+func (c *Client) webURL() string {
+	u := *c.BaseURL
+	u.Host = strings.TrimPrefix(u.Host, "api.")
+	u.Path = "/"
+	u.RawPath = ""
+	return u.String()
+}
+
+// DefaultCCTrayTTL is how long CCTrayHandler caches the feed by default
+const DefaultCCTrayTTL = 30 * time.Second
+
+// CCTrayHandler is an http.Handler serving the CCTray feed of a set of branches.
+//
+// The feed is cached for TTL, as radiators poll it constantly. When Travis CI
+// cannot be reached, the last feed fetched is served, or 502 Bad Gateway if there is none.
+type CCTrayHandler struct {
+	// How long the feed is cached, DefaultCCTrayTTL when 0
+	TTL time.Duration
+
+	client  *Client
+	targets []*CCTrayTarget
+
+	mu        sync.Mutex
+	feed      []byte
+	fetchedAt time.Time
+
+	// fetchMu serializes the fetches of the feed, so concurrent polls share one
+	fetchMu sync.Mutex
+
+	// now returns the current time, replaced in tests
+	now func() time.Time
+}
+
+// NewCCTrayHandler returns a CCTrayHandler serving the feed of targets
+func NewCCTrayHandler(client *Client, targets ...*CCTrayTarget) *CCTrayHandler {
+	return &CCTrayHandler{client: client, targets: targets, now: time.Now}
+}
+
+// ServeHTTP serves the CCTray feed
+func (h *CCTrayHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	out, err := h.render(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write(out)
+}
+
+// render returns the cached feed, fetching it when it expired
+func (h *CCTrayHandler) render(r *http.Request) ([]byte, error) {
+	if out, ok := h.cached(); ok {
+		return out, nil
+	}
+
+	h.fetchMu.Lock()
+	defer h.fetchMu.Unlock()
+
+	// Another poll may have fetched the feed while this one was waiting
+	if out, ok := h.cached(); ok {
+		return out, nil
+	}
+
+	var out []byte
+	feed, _, err := h.client.CCTray(r.Context(), h.targets)
+	if err == nil {
+		out, err = xml.MarshalIndent(feed, "", "  ")
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err != nil {
+		if h.feed != nil {
+			return h.feed, nil
+		}
+		return nil, err
+	}
+
+	h.feed, h.fetchedAt = append([]byte(xml.Header), out...), h.now()
+
+	return h.feed, nil
+}
+
+// cached returns the cached feed unless it expired
+func (h *CCTrayHandler) cached() ([]byte, bool) {
+	ttl := h.TTL
+	if ttl == 0 {
+		ttl = DefaultCCTrayTTL
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.feed != nil && h.now().Sub(h.fetchedAt) < ttl {
+		return h.feed, true
+	}
+	return nil, false
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_CCTray(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc(fmt.Sprintf("/repo/%s", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, `{"slug":"shuheiktgw/go-travis-test","default_branch":{"name":"master"}}`)
+	})
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/branch/master", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, `{"name":"master","last_build":{"id":10,"number":"10","state":"passed","finished_at":"2020-01-01T00:00:00Z"}}`)
+	})
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/branch/develop", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"name":"develop","last_build":{"id":12,"number":"12","state":"started"}}`)
+	})
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/builds", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		testFormValues(t, r, values{"branch.name": "develop", "state": "passed,failed,errored,canceled", "limit": "1"})
+		fmt.Fprint(w, `{"builds":[{"id":11,"number":"11","state":"failed","finished_at":"2020-01-02T00:00:00Z"}]}`)
+	})
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/branch/missing", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error_type":"not_found","error_message":"branch not found"}`)
+	})
+	mux.HandleFunc("/repo/shuheiktgw/deleted", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error_type":"not_found","error_message":"repository not found"}`)
+	})
+
+	targets := []*CCTrayTarget{
+		{RepoSlug: testRepoSlug},
+		{RepoSlug: testRepoSlug, Branch: "develop"},
+		{RepoSlug: testRepoSlug, Branch: "missing"},
+		{RepoSlug: "shuheiktgw/deleted"},
+	}
+	feed, _, err := client.CCTray(context.Background(), targets)
+
+	if err != nil {
+		t.Fatalf("Client.CCTray returned error: %v", err)
+	}
+
+	web := client.webURL() + testRepoSlug
+	want := []*CCTrayProject{
+		{Name: "shuheiktgw/go-travis-test (master)", Activity: "Sleeping", LastBuildStatus: "Success", LastBuildLabel: "10", LastBuildTime: "2020-01-01T00:00:00Z", WebUrl: web + "/builds/10"},
+		{Name: "shuheiktgw/go-travis-test (develop)", Activity: "Building", LastBuildStatus: "Failure", LastBuildLabel: "11", LastBuildTime: "2020-01-02T00:00:00Z", WebUrl: web + "/builds/12"},
+		{Name: "shuheiktgw/go-travis-test (missing)", Activity: "Sleeping", LastBuildStatus: "Unknown", WebUrl: web},
+		{Name: "shuheiktgw/deleted", Activity: "Sleeping", LastBuildStatus: "Unknown", WebUrl: client.webURL() + "shuheiktgw/deleted"},
+	}
+	if !reflect.DeepEqual(feed.Projects, want) {
+		t.Errorf("Client.CCTray returned %+v, want %+v", feed.Projects, want)
+	}
+
+	w := httptest.NewRecorder()
+	NewCCTrayHandler(client, targets[1]).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/cc.xml", nil))
+
+	wantXML := `<Projects>
+  <Project name="shuheiktgw/go-travis-test (develop)" activity="Building" lastBuildStatus="Failure" lastBuildLabel="11" lastBuildTime="2020-01-02T00:00:00Z" webUrl="` + web + `/builds/12"></Project>
+</Projects>`
+	if got := w.Body.String(); !strings.HasSuffix(got, wantXML) {
+		t.Errorf("CCTrayHandler.ServeHTTP returned\n%s\nwant\n%s", got, wantXML)
+	}
+}
+
+func TestCCTrayHandler_cache(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	state, fetched := BuildStatePassed, 0
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/branch/master", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		fetched++
+		if state == "" {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"error_type":"internal","error_message":"unavailable"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"name":"master","last_build":{"id":10,"number":"10","state":%q}}`, state)
+	})
+
+	now := time.Now()
+	h := NewCCTrayHandler(client, &CCTrayTarget{RepoSlug: testRepoSlug, Branch: "master"})
+	h.now = func() time.Time { return now }
+
+	get := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/cc.xml", nil))
+		return w
+	}
+
+	if w := get(); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `lastBuildStatus="Success"`) {
+		t.Fatalf("CCTrayHandler.ServeHTTP returned %d\n%s", w.Code, w.Body.String())
+	}
+
+	// The feed is cached until it expires
+	state = BuildStateFailed
+	if w := get(); !strings.Contains(w.Body.String(), `lastBuildStatus="Success"`) {
+		t.Errorf("CCTrayHandler.ServeHTTP returned\n%s\nwant the cached feed", w.Body.String())
+	}
+
+	now = now.Add(DefaultCCTrayTTL)
+	if w := get(); !strings.Contains(w.Body.String(), `lastBuildStatus="Failure"`) {
+		t.Errorf("CCTrayHandler.ServeHTTP returned\n%s\nwant a failure", w.Body.String())
+	}
+
+	// The last feed is served when Travis CI cannot be reached
+	state = ""
+	now = now.Add(DefaultCCTrayTTL)
+	if w := get(); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `lastBuildStatus="Failure"`) {
+		t.Errorf("CCTrayHandler.ServeHTTP returned %d\n%s\nwant the last feed", w.Code, w.Body.String())
+	}
+
+	if fetched != 3 {
+		t.Errorf("CCTrayHandler fetched the branch %d times, want 3", fetched)
+	}
+}
+
+func TestClient_webURL(t *testing.T) {
+	client := NewClient(ApiComUrl, "")
+
+	if got, want := client.webURL(), "https://travis-ci.com/"; got != want {
+		t.Errorf("Client.webURL returned %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,353 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// DefaultCreditsPerMinute are the credits consumed per minute of a job,
+// keyed by os, or by os/arch to override the rate of an architecture
+var DefaultCreditsPerMinute = map[string]float64{
+	"linux":   10,
+	"windows": 20,
+	"osx":     50,
+}
+
+// ConcurrencyOption specifies the optional parameters of AnalyzeConcurrency
+type ConcurrencyOption struct {
+	// Only jobs started in [Since, Until) are analyzed. AnalyzeConcurrencyByOwner
+	// defaults Until to now and Since to 30 days before Until.
+	Since time.Time
+	Until time.Time
+	// The number of concurrent jobs allowed by the plan. Defaults to the observed peak.
+	Limit int
+	// The credits consumed per minute, keyed by os or os/arch. Defaults to DefaultCreditsPerMinute.
+	CreditsPerMinute map[string]float64
+}
+
+// ConcurrencyPoint is a change of the number of running jobs
+type ConcurrencyPoint struct {
+	Time    time.Time `json:"time"`
+	Running int       `json:"running"`
+}
+
+// PlatformUsage is the usage of an os and architecture
+type PlatformUsage struct {
+	Os   string `json:"os"`
+	Arch string `json:"arch"`
+	Jobs int    `json:"jobs"`
+	// The time the jobs ran, in minutes
+	Minutes float64 `json:"minutes"`
+	// The minutes billed, each job being rounded up to a whole minute
+	BilledMinutes int `json:"billed_minutes"`
+	// The estimated credits consumed, BilledMinutes times the rate of the platform
+	Credits float64 `json:"credits"`
+}
+
+// ConcurrencyReport is the result of AnalyzeConcurrency
+type ConcurrencyReport struct {
+	// How many finished jobs were analyzed
+	Jobs int `json:"jobs"`
+	// The number of running jobs over time
+	Timeline []*ConcurrencyPoint `json:"timeline"`
+	// The maximum number of jobs running at once, and when it was first reached
+	Peak   int       `json:"peak"`
+	PeakAt time.Time `json:"peak_at"`
+	// The maximum number of jobs running at once, by os and by queue
+	PeakByOs    map[string]int `json:"peak_by_os"`
+	PeakByQueue map[string]int `json:"peak_by_queue"`
+	// The concurrency limit the queue time is computed against
+	Limit int `json:"limit"`
+	// The total time jobs waited to start while the limit was reached,
+	// encoded to JSON in seconds
+	QueuedAtLimit time.Duration `json:"queued_at_limit_seconds"`
+	// The usage of each platform, by os then arch
+	Platforms []*PlatformUsage `json:"platforms"`
+	// The totals of the platforms
+	Minutes float64 `json:"minutes"`
+	Credits float64 `json:"credits"`
+}
+
+// MarshalJSON encodes the report with QueuedAtLimit in seconds
+func (r ConcurrencyReport) MarshalJSON() ([]byte, error) {
+	type report ConcurrencyReport
+	return json.Marshal(struct {
+		report
+		QueuedAtLimit jsonSeconds `json:"queued_at_limit_seconds"`
+	}{report(r), jsonSeconds(r.QueuedAtLimit)})
+}
+
+// UnmarshalJSON decodes the report with QueuedAtLimit in seconds
+func (r *ConcurrencyReport) UnmarshalJSON(data []byte) error {
+	type report ConcurrencyReport
+	v := struct {
+		*report
+		QueuedAtLimit jsonSeconds `json:"queued_at_limit_seconds"`
+	}{report: (*report)(r)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	r.QueuedAtLimit = time.Duration(v.QueuedAtLimit)
+	return nil
+}
+
+// concurrencyJob is a finished job reduced to what is analyzed
+type concurrencyJob struct {
+	created, started, finished time.Time
+	os, arch, queue            string
+}
+
+// AnalyzeConcurrency reconstructs the number of jobs running at once from
+// finished jobs, e.g. listed by JobsService.List with the job.config include,
+// and reports the peak concurrency, the time jobs were queued because the
+// concurrency limit was reached, and the minutes and estimated credits used
+// per os and architecture. Jobs without an os or arch run on linux and amd64.
+func AnalyzeConcurrency(jobs []*Job, opt *ConcurrencyOption) *ConcurrencyReport {
+	var o ConcurrencyOption
+	if opt != nil {
+		o = *opt
+	}
+	if o.CreditsPerMinute == nil {
+		o.CreditsPerMinute = DefaultCreditsPerMinute
+	}
+
+	var js []*concurrencyJob
+	for _, j := range jobs {
+		started, ok1 := parseTime(j.StartedAt)
+		finished, ok2 := parseTime(j.FinishedAt)
+		if !ok1 || !ok2 || finished.Before(started) {
+			continue
+		}
+		if (!o.Since.IsZero() && started.Before(o.Since)) || (!o.Until.IsZero() && !started.Before(o.Until)) {
+			continue
+		}
+
+		cj := &concurrencyJob{started: started, finished: finished, os: "linux", arch: "amd64"}
+		if created, ok := parseTime(j.CreatedAt); ok && !created.After(started) {
+			cj.created = created
+		} else {
+			cj.created = started
+		}
+		if j.Config != nil && j.Config.Os != nil && *j.Config.Os != "" {
+			cj.os = *j.Config.Os
+		}
+		if j.Config != nil && j.Config.Arch != nil && *j.Config.Arch != "" {
+			cj.arch = *j.Config.Arch
+		}
+		if j.Queue != nil {
+			cj.queue = *j.Queue
+		}
+		js = append(js, cj)
+	}
+
+	report := &ConcurrencyReport{
+		Jobs:        len(js),
+		PeakByOs:    map[string]int{},
+		PeakByQueue: map[string]int{},
+	}
+
+	report.Timeline = concurrencyTimeline(js, func(*concurrencyJob) string { return "" })[""]
+	for _, p := range report.Timeline {
+		if p.Running > report.Peak {
+			report.Peak = p.Running
+			report.PeakAt = p.Time
+		}
+	}
+	for os, timeline := range concurrencyTimeline(js, func(j *concurrencyJob) string { return j.os }) {
+		report.PeakByOs[os] = peakConcurrency(timeline)
+	}
+	for queue, timeline := range concurrencyTimeline(js, func(j *concurrencyJob) string { return j.queue }) {
+		if queue != "" {
+			report.PeakByQueue[queue] = peakConcurrency(timeline)
+		}
+	}
+
+	report.Limit = o.Limit
+	if report.Limit == 0 {
+		report.Limit = report.Peak
+	}
+	if report.Limit > 0 {
+		for _, j := range js {
+			report.QueuedAtLimit += timeAtLimit(report.Timeline, j.created, j.started, report.Limit)
+		}
+	}
+
+	platforms := map[[2]string]*PlatformUsage{}
+	for _, j := range js {
+		key := [2]string{j.os, j.arch}
+		p, ok := platforms[key]
+		if !ok {
+			p = &PlatformUsage{Os: j.os, Arch: j.arch}
+			platforms[key] = p
+			report.Platforms = append(report.Platforms, p)
+		}
+
+		minutes := j.finished.Sub(j.started).Minutes()
+		p.Jobs++
+		p.Minutes += minutes
+		p.BilledMinutes += int(math.Ceil(minutes))
+	}
+
+	sort.Slice(report.Platforms, func(i, j int) bool {
+		a, b := report.Platforms[i], report.Platforms[j]
+		if a.Os != b.Os {
+			return a.Os < b.Os
+		}
+		return a.Arch < b.Arch
+	})
+	for _, p := range report.Platforms {
+		rate, ok := o.CreditsPerMinute[p.Os+"/"+p.Arch]
+		if !ok {
+			rate = o.CreditsPerMinute[p.Os]
+		}
+		p.Credits = float64(p.BilledMinutes) * rate
+		report.Minutes += p.Minutes
+		report.Credits += p.Credits
+	}
+
+	return report
+}
+
+// concurrencyTimeline returns the number of running jobs over time, grouped by key.
+// Jobs finishing when others start are not counted as running at once.
+func concurrencyTimeline(jobs []*concurrencyJob, key func(*concurrencyJob) string) map[string][]*ConcurrencyPoint {
+	type change struct {
+		t     time.Time
+		delta int
+	}
+
+	changes := map[string][]change{}
+	for _, j := range jobs {
+		k := key(j)
+		changes[k] = append(changes[k], change{j.started, 1}, change{j.finished, -1})
+	}
+
+	timelines := map[string][]*ConcurrencyPoint{}
+	for k, cs := range changes {
+		sort.Slice(cs, func(i, j int) bool {
+			if !cs[i].t.Equal(cs[j].t) {
+				return cs[i].t.Before(cs[j].t)
+			}
+			return cs[i].delta < cs[j].delta
+		})
+
+		var timeline []*ConcurrencyPoint
+		running := 0
+		for _, c := range cs {
+			running += c.delta
+			if n := len(timeline); n > 0 && timeline[n-1].Time.Equal(c.t) {
+				timeline[n-1].Running = running
+				continue
+			}
+			timeline = append(timeline, &ConcurrencyPoint{Time: c.t, Running: running})
+		}
+		timelines[k] = timeline
+	}
+
+	return timelines
+}
+
+func peakConcurrency(timeline []*ConcurrencyPoint) int {
+	peak := 0
+	for _, p := range timeline {
+		if p.Running > peak {
+			peak = p.Running
+		}
+	}
+	return peak
+}
+
+// timeAtLimit returns how long the number of running jobs
+// was at least limit between from and to
+func timeAtLimit(timeline []*ConcurrencyPoint, from, to time.Time, limit int) time.Duration {
+	if !to.After(from) {
+		return 0
+	}
+
+	// The first change after from
+	i := sort.Search(len(timeline), func(i int) bool { return timeline[i].Time.After(from) })
+
+	var total time.Duration
+	running := 0
+	if i > 0 {
+		running = timeline[i-1].Running
+	}
+	start := from
+	for ; start.Before(to); i++ {
+		end := to
+		if i < len(timeline) && timeline[i].Time.Before(to) {
+			end = timeline[i].Time
+		}
+		if running >= limit {
+			total += end.Sub(start)
+		}
+		if i >= len(timeline) {
+			break
+		}
+		running = timeline[i].Running
+		start = end
+	}
+
+	return total
+}
+
+// AnalyzeConcurrencyByOwner analyzes the concurrency of the jobs of the builds
+// of all the repositories of an owner, see AnalyzeConcurrency
+func (c *Client) AnalyzeConcurrencyByOwner(ctx context.Context, owner string, opt *ConcurrencyOption) (*ConcurrencyReport, *http.Response, error) {
+	var o ConcurrencyOption
+	if opt != nil {
+		o = *opt
+	}
+	if o.Until.IsZero() {
+		o.Until = time.Now()
+	}
+	if o.Since.IsZero() {
+		o.Since = o.Until.AddDate(0, 0, -30)
+	}
+
+	repos, resp, err := c.Repositories.listAllByOwner(ctx, owner)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	var jobs []*Job
+	listOpt := BuildsByRepoOption{
+		Include: []string{BuildInclude.Jobs, JobInclude.Config},
+		SortBy:  SortOrder{SortDesc(BuildSortFieldId)},
+	}
+
+	for _, repo := range repos {
+		if repo.Slug == nil {
+			continue
+		}
+
+		resp, err = c.Builds.walkByRepoSlug(ctx, *repo.Slug, listOpt, func(b *Build) bool {
+			started, ok := parseTime(b.StartedAt)
+			if !ok {
+				return true
+			}
+			// Builds are listed most recently created first, and their jobs
+			// cannot start before them. A build restarted long after it was
+			// created may still run within the window, and is missed.
+			if started.Before(o.Since) {
+				return false
+			}
+			jobs = append(jobs, b.Jobs...)
+			return true
+		})
+		if err != nil {
+			return nil, resp, err
+		}
+	}
+
+	return AnalyzeConcurrency(jobs, &o), resp, nil
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testConcurrencyJobs = `[
+  {"id":1,"queue":"q1","created_at":"2020-01-01T00:00:00Z","started_at":"2020-01-01T00:00:00Z","finished_at":"2020-01-01T00:10:00Z"},
+  {"id":2,"queue":"q1","created_at":"2020-01-01T00:00:00Z","started_at":"2020-01-01T00:05:00Z","finished_at":"2020-01-01T00:15:00Z","config":{"os":"linux"}},
+  {"id":3,"queue":"q2","created_at":"2020-01-01T00:02:00Z","started_at":"2020-01-01T00:10:00Z","finished_at":"2020-01-01T00:20:00Z","config":{"os":"osx"}},
+  {"id":4,"queue":"q1","created_at":"2020-01-01T00:12:00Z","started_at":"2020-01-01T00:15:00Z","finished_at":"2020-01-01T00:16:30Z","config":{"os":"linux","arch":"arm64"}},
+  {"id":5,"queue":"q1","created_at":"2020-01-01T00:12:00Z","started_at":"2020-01-01T00:15:00Z"}
+]`
+
+func TestAnalyzeConcurrency(t *testing.T) {
+	var jobs []*Job
+	if err := json.Unmarshal([]byte(testConcurrencyJobs), &jobs); err != nil {
+		t.Fatal(err)
+	}
+
+	report := AnalyzeConcurrency(jobs, nil)
+
+	at := func(minutes, seconds int) time.Time {
+		return time.Date(2020, 1, 1, 0, minutes, seconds, 0, time.UTC)
+	}
+
+	var timeline []string
+	for _, p := range report.Timeline {
+		timeline = append(timeline, fmt.Sprintf("%s %d", p.Time.Format("15:04:05"), p.Running))
+	}
+	wantTimeline := []string{"00:00:00 1", "00:05:00 2", "00:10:00 2", "00:15:00 2", "00:16:30 1", "00:20:00 0"}
+	if !reflect.DeepEqual(timeline, wantTimeline) {
+		t.Errorf("AnalyzeConcurrency returned timeline %q, want %q", timeline, wantTimeline)
+	}
+
+	if report.Jobs != 4 || report.Peak != 2 || !report.PeakAt.Equal(at(5, 0)) || report.Limit != 2 {
+		t.Errorf("AnalyzeConcurrency returned %d jobs, peak %d at %v, limit %d", report.Jobs, report.Peak, report.PeakAt, report.Limit)
+	}
+	if want := map[string]int{"linux": 2, "osx": 1}; !reflect.DeepEqual(report.PeakByOs, want) {
+		t.Errorf("AnalyzeConcurrency returned peaks by os %v, want %v", report.PeakByOs, want)
+	}
+	if want := map[string]int{"q1": 2, "q2": 1}; !reflect.DeepEqual(report.PeakByQueue, want) {
+		t.Errorf("AnalyzeConcurrency returned peaks by queue %v, want %v", report.PeakByQueue, want)
+	}
+	if want := 8 * time.Minute; report.QueuedAtLimit != want {
+		t.Errorf("AnalyzeConcurrency returned %v queued at limit, want %v", report.QueuedAtLimit, want)
+	}
+
+	// QueuedAtLimit is encoded to JSON in seconds
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("json.Marshal returned error: %v", err)
+	}
+	if !strings.Contains(string(data), `"queued_at_limit_seconds":480`) {
+		t.Errorf("json.Marshal returned %s", data)
+	}
+	var decoded ConcurrencyReport
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.QueuedAtLimit != report.QueuedAtLimit || decoded.Peak != report.Peak {
+		t.Errorf("json.Unmarshal returned %+v, %v", decoded, err)
+	}
+
+	wantPlatforms := []*PlatformUsage{
+		{Os: "linux", Arch: "amd64", Jobs: 2, Minutes: 20, BilledMinutes: 20, Credits: 200},
+		{Os: "linux", Arch: "arm64", Jobs: 1, Minutes: 1.5, BilledMinutes: 2, Credits: 20},
+		{Os: "osx", Arch: "amd64", Jobs: 1, Minutes: 10, BilledMinutes: 10, Credits: 500},
+	}
+	if !reflect.DeepEqual(report.Platforms, wantPlatforms) {
+		t.Errorf("AnalyzeConcurrency returned platforms %+v, want %+v", report.Platforms, wantPlatforms)
+	}
+	if report.Minutes != 31.5 || report.Credits != 720 {
+		t.Errorf("AnalyzeConcurrency returned %v minutes and %v credits", report.Minutes, report.Credits)
+	}
+
+	report = AnalyzeConcurrency(jobs, &ConcurrencyOption{
+		Limit:            3,
+		CreditsPerMinute: map[string]float64{"linux": 10, "linux/arm64": 0, "osx": 50},
+	})
+	if report.QueuedAtLimit != 0 || report.Credits != 700 {
+		t.Errorf("AnalyzeConcurrency returned %v queued at limit and %v credits", report.QueuedAtLimit, report.Credits)
+	}
+
+	report = AnalyzeConcurrency(jobs, &ConcurrencyOption{Since: at(10, 0), Until: at(15, 0)})
+	if report.Jobs != 1 {
+		t.Errorf("AnalyzeConcurrency analyzed %d jobs, want 1", report.Jobs)
+	}
+}
+
+func TestClient_AnalyzeConcurrencyByOwner(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc(fmt.Sprintf("/owner/%s/repos", testOwner), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, `{"repositories":[{"id":1,"slug":"shuheiktgw/go-travis-test"}]}`)
+	})
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/builds", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		testFormValues(t, r, values{"include": "build.jobs,job.config", "limit": "100", "sort_by": "id:desc"})
+		fmt.Fprintf(w, `{"builds":[
+		  {"id":2,"started_at":"2020-01-01T00:00:00Z","jobs":%s},
+		  {"id":1,"started_at":"2019-12-01T00:00:00Z","jobs":[{"id":10,"started_at":"2019-12-01T00:00:00Z","finished_at":"2019-12-01T00:10:00Z"}]}
+		]}`, testConcurrencyJobs)
+	})
+
+	opt := &ConcurrencyOption{Until: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)}
+	report, _, err := client.AnalyzeConcurrencyByOwner(context.Background(), testOwner, opt)
+
+	if err != nil {
+		t.Fatalf("Client.AnalyzeConcurrencyByOwner returned error: %v", err)
+	}
+	if report.Jobs != 4 || report.Peak != 2 {
+		t.Errorf("Client.AnalyzeConcurrencyByOwner returned %d jobs and peak %d, want 4 and 2", report.Jobs, report.Peak)
+	}
+}
@@ -8,6 +8,7 @@ package travis
 // Config represents Travis CI job's configuration
 type Config struct {
 	Os            *string            `json:"os,omitempty"`
+	Arch          *string            `json:"arch,omitempty"`
 	Env           *string            `json:"env,omitempty"`
 	Rvm           *string            `json:"rvm,omitempty"`
 	Dist          *string            `json:"dist,omitempty"`
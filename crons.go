@@ -264,3 +264,45 @@ func (cs *CronsService) Delete(ctx context.Context, id uint) (*http.Response, er
 
 	return resp, err
 }
+
+// replaceByRepoSlug replaces the cron with the provided id of a branch of given repository slug.
+// The new cron is created before the existing one is deleted, so the branch keeps its cron
+// when the creation fails. The API may replace the existing cron itself, so it is only deleted
+// when it differs from the created one, and it not existing anymore is not an error.
+// When only the deletion fails, the created cron is returned along with the error.
+func (cs *CronsService) replaceByRepoSlug(ctx context.Context, repoSlug string, branchName string, id uint, cron *CronBody) (*Cron, *http.Response, error) {
+	created, resp, err := cs.CreateByRepoSlug(ctx, repoSlug, branchName, cron)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	if created.Id != nil && *created.Id == id {
+		return created, resp, nil
+	}
+
+	resp, err = cs.Delete(ctx, id)
+	if err != nil && (resp == nil || resp.StatusCode != http.StatusNotFound) {
+		return created, resp, fmt.Errorf("travis: the cron was created but the existing cron %d could not be deleted: %v", id, err)
+	}
+
+	return created, resp, nil
+}
+
+// listAllByRepoSlug fetches all the crons of given repository slug, page by page
+func (cs *CronsService) listAllByRepoSlug(ctx context.Context, repoSlug string) ([]*Cron, *http.Response, error) {
+	var all []*Cron
+
+	opt := CronsOption{Limit: 100}
+	for {
+		crons, resp, err := cs.ListByRepoSlug(ctx, repoSlug, &opt)
+		if err != nil {
+			return nil, resp, err
+		}
+
+		all = append(all, crons...)
+		if len(crons) < opt.Limit {
+			return all, resp, nil
+		}
+		opt.Offset += opt.Limit
+	}
+}
@@ -191,3 +191,56 @@ func TestCronsService_Delete(t *testing.T) {
 		t.Errorf("Cron.Delete returned error: %v", err)
 	}
 }
+
+func TestCronsService_replaceByRepoSlug(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var requests []string
+	createStatus, deleteStatus := http.StatusOK, http.StatusNoContent
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/branch/master/cron", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPost)
+		requests = append(requests, "POST")
+		w.WriteHeader(createStatus)
+		if createStatus != http.StatusOK {
+			fmt.Fprint(w, `{"error_type":"insufficient_access","error_message":"forbidden"}`)
+			return
+		}
+		fmt.Fprint(w, `{"id":2}`)
+	})
+	mux.HandleFunc("/cron/1", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodDelete)
+		requests = append(requests, "DELETE")
+		w.WriteHeader(deleteStatus)
+		if deleteStatus != http.StatusNoContent {
+			fmt.Fprint(w, `{"error_type":"not_found","error_message":"cron not found"}`)
+		}
+	})
+
+	cases := []struct {
+		createStatus, deleteStatus int
+		wantRequests               []string
+		wantCreated, wantErr       bool
+	}{
+		{http.StatusOK, http.StatusNoContent, []string{"POST", "DELETE"}, true, false},
+		// The API replaced the existing cron itself
+		{http.StatusOK, http.StatusNotFound, []string{"POST", "DELETE"}, true, false},
+		// The existing cron is kept when the creation fails
+		{http.StatusForbidden, http.StatusNoContent, []string{"POST"}, false, true},
+		{http.StatusOK, http.StatusForbidden, []string{"POST", "DELETE"}, true, true},
+	}
+	for i, c := range cases {
+		requests = nil
+		createStatus, deleteStatus = c.createStatus, c.deleteStatus
+
+		body := &CronBody{Interval: CronIntervalWeekly}
+		cron, _, err := client.Crons.replaceByRepoSlug(context.Background(), testRepoSlug, "master", 1, body)
+
+		if (err != nil) != c.wantErr || (cron != nil) != c.wantCreated {
+			t.Errorf("#%d Crons.replaceByRepoSlug returned %+v, %v", i, cron, err)
+		}
+		if !reflect.DeepEqual(requests, c.wantRequests) {
+			t.Errorf("#%d Crons.replaceByRepoSlug made requests %v, want %v", i, requests, c.wantRequests)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"strings"
+)
+
+// Encrypt encrypts plaintext with the provided PEM encoded RSA public key
+// the same way the `travis encrypt` command does, and returns the base64
+// encoded ciphertext to be used as a secure value in .travis.yml:
+//
+//	env:
+//	  global:
+//	    - secure: "<ciphertext>"
+//
+// To encrypt an environment variable, plaintext should be of the form NAME=value.
+func Encrypt(publicKeyPEM string, plaintext string) (string, error) {
+	key, err := parsePublicKey(publicKeyPEM)
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err := rsa.EncryptPKCS1v15(rand.Reader, key, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// EncryptForRepo fetches the public key of the repository with the provided slug
+// and encrypts plaintext with it, see Encrypt
+//
+// Travis CI API docs: https://developer.travis-ci.com/resource/key_pair_generated#find
+func (rs *RepositoriesService) EncryptForRepo(ctx context.Context, slug string, plaintext string) (string, *http.Response, error) {
+	keyPair, resp, err := rs.client.GeneratedKeyPair.FindByRepoSlug(ctx, slug)
+	if err != nil {
+		return "", resp, err
+	}
+
+	if keyPair.PublicKey == nil {
+		return "", resp, errors.New("travis: the repository has no public key")
+	}
+
+	ciphertext, err := Encrypt(*keyPair.PublicKey, plaintext)
+	return ciphertext, resp, err
+}
+
+// Decrypt decrypts a base64 encoded secure value with the provided
+// PEM encoded RSA private key, the counterpart of Encrypt
+func Decrypt(privateKeyPEM string, ciphertext string) (string, error) {
+	key, err := parsePrivateKey(privateKeyPEM)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(ciphertext))
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := rsa.DecryptPKCS1v15(nil, key, data)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+// Fingerprint returns the fingerprint of an RSA public key in the format of
+// KeyPair.Fingerprint, i.e. the colon separated MD5 digest of the key in the
+// SSH wire format. publicKey is either PEM encoded or in the OpenSSH
+// authorized_keys format.
+func Fingerprint(publicKey string) (string, error) {
+	blob, err := sshPublicKeyBlob(publicKey)
+	if err != nil {
+		return "", err
+	}
+
+	sum := md5.Sum(blob)
+	hexSum := hex.EncodeToString(sum[:])
+
+	pairs := make([]string, 0, len(sum))
+	for i := 0; i < len(hexSum); i += 2 {
+		pairs = append(pairs, hexSum[i:i+2])
+	}
+
+	return strings.Join(pairs, ":"), nil
+}
+
+// parsePublicKey parses an RSA public key, either PEM encoded or
+// in the OpenSSH authorized_keys format.
+// The Travis CI API labels its keys as RSA PUBLIC KEY regardless of their
+// encoding, so both PKIX and PKCS #1 encodings are accepted whatever the label.
+func parsePublicKey(publicKey string) (*rsa.PublicKey, error) {
+	if isSSHPublicKey(publicKey) {
+		blob, err := sshPublicKeyBlob(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		return parseSSHPublicKeyBlob(blob)
+	}
+
+	block, _ := pem.Decode([]byte(publicKey))
+	if block == nil {
+		return nil, errors.New("travis: failed to decode the PEM encoded public key")
+	}
+
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("travis: the public key is not an RSA key")
+		}
+		return rsaKey, nil
+	}
+
+	return x509.ParsePKCS1PublicKey(block.Bytes)
+}
+
+// parsePrivateKey parses a PEM encoded RSA private key
+// in either the PKCS #1 or the PKCS #8 encoding
+func parsePrivateKey(privateKeyPEM string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(privateKeyPEM))
+	if block == nil {
+		return nil, errors.New("travis: failed to decode the PEM encoded private key")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("travis: the private key is not an RSA key")
+		}
+		return rsaKey, nil
+	}
+
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
+
+const sshRSAKeyType = "ssh-rsa"
+
+func isSSHPublicKey(publicKey string) bool {
+	return strings.HasPrefix(strings.TrimSpace(publicKey), sshRSAKeyType+" ")
+}
+
+// sshPublicKeyBlob returns the SSH wire format of an RSA public key
+func sshPublicKeyBlob(publicKey string) ([]byte, error) {
+	if isSSHPublicKey(publicKey) {
+		fields := strings.Fields(publicKey)
+		if len(fields) < 2 {
+			return nil, errors.New("travis: malformed SSH public key")
+		}
+		return base64.StdEncoding.DecodeString(fields[1])
+	}
+
+	key, err := parsePublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var blob bytes.Buffer
+	writeSSHString(&blob, []byte(sshRSAKeyType))
+	writeSSHString(&blob, sshMPInt(big.NewInt(int64(key.E))))
+	writeSSHString(&blob, sshMPInt(key.N))
+
+	return blob.Bytes(), nil
+}
+
+// parseSSHPublicKeyBlob parses the SSH wire format of an RSA public key
+func parseSSHPublicKeyBlob(blob []byte) (*rsa.PublicKey, error) {
+	var fields [][]byte
+	for len(blob) > 0 {
+		if len(blob) < 4 {
+			return nil, errors.New("travis: malformed SSH public key")
+		}
+		n := binary.BigEndian.Uint32(blob)
+		if uint32(len(blob)-4) < n {
+			return nil, errors.New("travis: malformed SSH public key")
+		}
+		fields = append(fields, blob[4:4+n])
+		blob = blob[4+n:]
+	}
+
+	if len(fields) != 3 || string(fields[0]) != sshRSAKeyType {
+		return nil, errors.New("travis: the public key is not an RSA key")
+	}
+
+	e := new(big.Int).SetBytes(fields[1])
+	if !e.IsInt64() || e.Int64() > 1<<31-1 {
+		return nil, errors.New("travis: malformed SSH public key")
+	}
+
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(fields[2]), E: int(e.Int64())}, nil
+}
+
+func writeSSHString(buf *bytes.Buffer, b []byte) {
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(b)))
+	buf.Write(length[:])
+	buf.Write(b)
+}
+
+// sshMPInt encodes a positive integer as an SSH mpint,
+// which needs a leading zero byte when the high bit is set
+func sshMPInt(n *big.Int) []byte {
+	b := n.Bytes()
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	return b
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// EncryptedFile describes a file encrypted with EncryptFile
+type EncryptedFile struct {
+	// Name of the private environment variable holding the hex encoded key
+	KeyVar string
+	// Name of the private environment variable holding the hex encoded initialization vector
+	IVVar string
+	// The hex encoded key
+	Key string
+	// The hex encoded initialization vector
+	IV string
+	// Command decrypting the file, to be added to before_install in .travis.yml
+	DecryptCommand string
+}
+
+// EncryptFile encrypts a file the same way the `travis encrypt-file` command does.
+// The content read from in is encrypted with a random AES-256 key and
+// initialization vector, which are stored as private environment variables
+// of the repository with the provided slug, and the ciphertext is written to out
+// in the format of `openssl aes-256-cbc`.
+//
+// path is the path of the file relative to the root of the repository.
+// It names the environment variables, and the ciphertext is expected to be
+// committed next to it with an .enc suffix for the returned decrypt command to work.
+// Encrypting the same path again updates the existing variables, like the command does.
+//
+// Travis CI API docs: https://developer.travis-ci.com/resource/env_vars#create
+func (rs *RepositoriesService) EncryptFile(ctx context.Context, slug string, path string, in io.Reader, out io.Writer) (*EncryptedFile, *http.Response, error) {
+	plaintext, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key := make([]byte, 32)
+	iv := make([]byte, aes.BlockSize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, nil, err
+	}
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, nil, err
+	}
+
+	ciphertext, err := encryptAES256CBC(key, iv, plaintext)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	prefix := encryptedFileEnvPrefix(path)
+	file := &EncryptedFile{
+		KeyVar: prefix + "_key",
+		IVVar:  prefix + "_iv",
+		Key:    hex.EncodeToString(key),
+		IV:     hex.EncodeToString(iv),
+	}
+	file.DecryptCommand = fmt.Sprintf(
+		"openssl aes-256-cbc -K $%s -iv $%s -in %s -out %s -d",
+		file.KeyVar,
+		file.IVVar,
+		shellEscape(path+".enc"),
+		shellEscape(path),
+	)
+
+	live, resp, err := rs.client.EnvVars.ListByRepoSlug(ctx, slug)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	// The variables are stored before the ciphertext is written,
+	// so no ciphertext is left behind without a way to decrypt it
+	matched := map[*EnvVar]bool{}
+	for _, v := range []*EnvVarBody{
+		{Name: file.KeyVar, Value: file.Key, Public: false},
+		{Name: file.IVVar, Value: file.IV, Public: false},
+	} {
+		if existing := matchEnvVar(live, matched, v); existing != nil {
+			_, resp, err = rs.client.EnvVars.UpdateByRepoSlug(ctx, slug, *existing.Id, v)
+		} else {
+			_, resp, err = rs.client.EnvVars.CreateByRepoSlug(ctx, slug, v)
+		}
+		if err != nil {
+			return nil, resp, err
+		}
+	}
+
+	if _, err := out.Write(ciphertext); err != nil {
+		return nil, resp, err
+	}
+
+	return file, resp, nil
+}
+
+// encryptedFileEnvPrefix returns the prefix of the names of the
+// environment variables holding the key and IV of an encrypted file,
+// e.g. encrypted_0a6446eb3ae3
+func encryptedFileEnvPrefix(path string) string {
+	sum := sha1.Sum([]byte(path))
+	return "encrypted_" + hex.EncodeToString(sum[:6])
+}
+
+// encryptAES256CBC encrypts plaintext with PKCS #7 padding,
+// which is what `openssl aes-256-cbc -K <key> -iv <iv>` expects
+func encryptAES256CBC(key, iv, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	padded := append(plaintext, bytes.Repeat([]byte{byte(padding)}, padding)...)
+
+	ciphertext := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, padded)
+
+	return ciphertext, nil
+}
+
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_./-]+$`)
+
+// shellEscape quotes s for use as a single word in a POSIX shell command
+func shellEscape(s string) string {
+	if shellSafe.MatchString(s) {
+		return s
+	}
+
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,207 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var (
+	testRSAKeyOnce sync.Once
+	testRSAKeyPriv *rsa.PrivateKey
+)
+
+// testRSAKey returns an RSA private key shared by the tests
+func testRSAKey(t *testing.T) *rsa.PrivateKey {
+	testRSAKeyOnce.Do(func() {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testRSAKeyPriv = key
+	})
+
+	return testRSAKeyPriv
+}
+
+func testPublicKeyPEM(t *testing.T, key *rsa.PrivateKey, label string, pkcs1 bool) string {
+	var der []byte
+	if pkcs1 {
+		der = x509.MarshalPKCS1PublicKey(&key.PublicKey)
+	} else {
+		var err error
+		der, err = x509.MarshalPKIXPublicKey(&key.PublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: label, Bytes: der}))
+}
+
+func testDecrypt(t *testing.T, key *rsa.PrivateKey, ciphertext string) string {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		t.Fatalf("ciphertext is not base64 encoded: %v", err)
+	}
+
+	plaintext, err := rsa.DecryptPKCS1v15(nil, key, data)
+	if err != nil {
+		t.Fatalf("failed to decrypt ciphertext: %v", err)
+	}
+
+	return string(plaintext)
+}
+
+func TestEncrypt(t *testing.T) {
+	key := testRSAKey(t)
+
+	cases := []struct {
+		label string
+		pkcs1 bool
+	}{
+		{"PUBLIC KEY", false},
+		// The Travis CI API labels PKIX encoded keys as RSA PUBLIC KEY
+		{"RSA PUBLIC KEY", false},
+		{"RSA PUBLIC KEY", true},
+	}
+
+	for i, c := range cases {
+		ciphertext, err := Encrypt(testPublicKeyPEM(t, key, c.label, c.pkcs1), "FOO=bar")
+		if err != nil {
+			t.Fatalf("#%d Encrypt returned error: %v", i, err)
+		}
+
+		if got := testDecrypt(t, key, ciphertext); got != "FOO=bar" {
+			t.Errorf("#%d Encrypt encrypted %q, want %q", i, got, "FOO=bar")
+		}
+	}
+}
+
+func TestEncrypt_invalidKey(t *testing.T) {
+	cases := []string{
+		"",
+		"not a key",
+		"-----BEGIN PUBLIC KEY-----\nbm90IGEga2V5\n-----END PUBLIC KEY-----\n",
+	}
+
+	for i, c := range cases {
+		if _, err := Encrypt(c, "FOO=bar"); err == nil {
+			t.Errorf("#%d Encrypt returned no error", i)
+		}
+	}
+}
+
+func TestRepositoriesService_EncryptForRepo(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	key := testRSAKey(t)
+	publicKey, _ := json.Marshal(testPublicKeyPEM(t, key, "RSA PUBLIC KEY", false))
+
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/key_pair/generated", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprintf(w, `{"description":"","public_key":%s,"fingerprint":"aa:bb"}`, publicKey)
+	})
+
+	ciphertext, _, err := client.Repositories.EncryptForRepo(context.Background(), testRepoSlug, "FOO=bar")
+
+	if err != nil {
+		t.Fatalf("Repositories.EncryptForRepo returned error: %v", err)
+	}
+
+	if got := testDecrypt(t, key, ciphertext); got != "FOO=bar" {
+		t.Errorf("Repositories.EncryptForRepo encrypted %q, want %q", got, "FOO=bar")
+	}
+}
+
+func TestRepositoriesService_EncryptForRepo_withoutPublicKey(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/key_pair/generated", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"description":""}`)
+	})
+
+	_, _, err := client.Repositories.EncryptForRepo(context.Background(), testRepoSlug, "FOO=bar")
+
+	if err == nil || !strings.Contains(err.Error(), "no public key") {
+		t.Errorf("Repositories.EncryptForRepo returned error %v, want missing public key", err)
+	}
+}
+
+func TestDecrypt(t *testing.T) {
+	key := testRSAKey(t)
+
+	ciphertext, err := Encrypt(testPublicKeyPEM(t, key, "PUBLIC KEY", false), "FOO=bar")
+	if err != nil {
+		t.Fatalf("Encrypt returned error: %v", err)
+	}
+
+	for i, der := range [][]byte{x509.MarshalPKCS1PrivateKey(key), testPKCS8(t, key)} {
+		privateKey := string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+
+		plaintext, err := Decrypt(privateKey, ciphertext)
+		if err != nil {
+			t.Fatalf("#%d Decrypt returned error: %v", i, err)
+		}
+		if plaintext != "FOO=bar" {
+			t.Errorf("#%d Decrypt returned %q, want %q", i, plaintext, "FOO=bar")
+		}
+	}
+}
+
+func testPKCS8(t *testing.T, key *rsa.PrivateKey) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func TestFingerprint(t *testing.T) {
+	const (
+		sshKey      = "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQDUAuRfjsToa2RgTAPgaQFY7vSfok+iI9typr90lp09q1SpmKBJ+0BmwxdPKOfCMhOqJ9V5dx1+PLmhZhieifqXs06NUgU9dH+U6ozGQ/rKEdd5GwbR/Pjj0nDeHg/7iVgOrJcShWzC4FXjt4RwGo/npCI2m6+f8o2MqIYLzd3a00+CdMEX15WEGBmHGmUIptaCJdS8v5Qb/nXWI8zx2q7VSv1+8p3EejgiWWyZGlQHBysTOfBhXDILqoJprAsP2dr37nze9M4FF9269DkXwANgyb4U7Ghp26+O5GZX43uwAOSnqZcVQ/cHCzFDOKzQwXYHaAWedt6mmCbDE5sCNmHj travis@example.com"
+		fingerprint = "9d:40:23:db:c6:e4:e2:3c:e3:18:33:9b:4c:03:e1:c2"
+	)
+
+	got, err := Fingerprint(sshKey)
+	if err != nil {
+		t.Fatalf("Fingerprint returned error: %v", err)
+	}
+	if got != fingerprint {
+		t.Errorf("Fingerprint returned %s, want %s", got, fingerprint)
+	}
+
+	// The PEM encoding of the same key has the same fingerprint
+	key, err := parsePublicKey(sshKey)
+	if err != nil {
+		t.Fatalf("parsePublicKey returned error: %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err = Fingerprint(string(pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: der})))
+	if err != nil {
+		t.Fatalf("Fingerprint returned error: %v", err)
+	}
+	if got != fingerprint {
+		t.Errorf("Fingerprint returned %s, want %s", got, fingerprint)
+	}
+}
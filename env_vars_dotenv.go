@@ -0,0 +1,443 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// ImportDotenvOption specifies options for importing a .env file
+type ImportDotenvOption struct {
+	// Whether to delete the environment variables missing from the file
+	Prune bool
+}
+
+// EnvVarsImportSummary reports the environment variables changed by an import.
+// Variables scoped to a branch are named NAME@branch.
+type EnvVarsImportSummary struct {
+	Created   []string `json:"created"`
+	Updated   []string `json:"updated"`
+	Unchanged []string `json:"unchanged"`
+	Deleted   []string `json:"deleted"`
+}
+
+var (
+	dotenvAnnotationPattern = regexp.MustCompile(`^#\s*travis:\s*(.*)$`)
+	dotenvNamePattern       = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// ParseDotenv parses a .env file into environment variables.
+//
+// Variables are private and not scoped to a branch by default. A comment of the
+// form `# travis: public, branch=develop` right before a variable changes that:
+//
+//	# travis: public
+//	GO_VERSION=1.14
+//	# travis: private, branch=production
+//	export API_TOKEN="s3cr3t"
+//
+// Values may be double quoted with escapes (\n, \", \\), single quoted
+// without escapes, or unquoted, in which case a " #" starts a comment.
+//
+// A variable annotated with keep and without a value, as written by WriteDotenv
+// for private variables, is a placeholder for an existing variable whose value
+// is unknown. Placeholders are left out of the result, and are kept as is by imports.
+func ParseDotenv(r io.Reader) ([]*EnvVarBody, error) {
+	vars, err := parseDotenv(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var envVars []*EnvVarBody
+	for _, v := range vars {
+		if !v.keep {
+			envVars = append(envVars, v.EnvVarBody)
+		}
+	}
+
+	return envVars, nil
+}
+
+// dotenvVar is a variable of a .env file
+type dotenvVar struct {
+	*EnvVarBody
+	// Whether the variable is a placeholder for an existing variable to keep as is
+	keep bool
+}
+
+func parseDotenv(r io.Reader) ([]*dotenvVar, error) {
+	var envVars []*dotenvVar
+	annotation, keep := &EnvVarBody{}, false
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+
+		if m := dotenvAnnotationPattern.FindStringSubmatch(text); m != nil {
+			a, k, err := parseDotenvAnnotation(m[1])
+			if err != nil {
+				return nil, fmt.Errorf("travis: line %d: %v", line, err)
+			}
+			annotation, keep = a, k
+			continue
+		}
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		text = strings.TrimPrefix(text, "export ")
+		i := strings.Index(text, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("travis: line %d: missing =", line)
+		}
+
+		name := strings.TrimSpace(text[:i])
+		if !dotenvNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("travis: line %d: invalid name %q", line, name)
+		}
+
+		value, err := parseDotenvValue(strings.TrimSpace(text[i+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("travis: line %d: %v", line, err)
+		}
+
+		envVars = append(envVars, &dotenvVar{
+			EnvVarBody: &EnvVarBody{Name: name, Value: value, Public: annotation.Public, Branch: annotation.Branch},
+			keep:       keep && value == "",
+		})
+		annotation, keep = &EnvVarBody{}, false
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return envVars, nil
+}
+
+func parseDotenvAnnotation(s string) (*EnvVarBody, bool, error) {
+	a, keep := &EnvVarBody{}, false
+
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+		switch {
+		case field == "public":
+			a.Public = true
+		case field == "private":
+			a.Public = false
+		case field == "keep":
+			keep = true
+		case strings.HasPrefix(field, "branch="):
+			a.Branch = strings.TrimPrefix(field, "branch=")
+		default:
+			return nil, false, fmt.Errorf("unknown annotation %q", field)
+		}
+	}
+
+	return a, keep, nil
+}
+
+func parseDotenvValue(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+
+	var value, rest string
+	switch s[0] {
+	case '"':
+		var b strings.Builder
+		i := 1
+		for ; i < len(s) && s[i] != '"'; i++ {
+			if s[i] == '\\' && i+1 < len(s) {
+				i++
+				switch s[i] {
+				case 'n':
+					b.WriteByte('\n')
+				case 't':
+					b.WriteByte('\t')
+				default:
+					b.WriteByte(s[i])
+				}
+				continue
+			}
+			b.WriteByte(s[i])
+		}
+		if i == len(s) {
+			return "", fmt.Errorf("unterminated double quoted value")
+		}
+		value, rest = b.String(), s[i+1:]
+	case '\'':
+		i := strings.IndexByte(s[1:], '\'')
+		if i < 0 {
+			return "", fmt.Errorf("unterminated single quoted value")
+		}
+		value, rest = s[1:i+1], s[i+2:]
+	default:
+		if i := strings.Index(s, " #"); i >= 0 {
+			s = s[:i]
+		}
+		return strings.TrimSpace(s), nil
+	}
+
+	if rest = strings.TrimSpace(rest); rest != "" && !strings.HasPrefix(rest, "#") {
+		return "", fmt.Errorf("unexpected %q after quoted value", rest)
+	}
+
+	return value, nil
+}
+
+// WriteDotenv writes environment variables in the format read by ParseDotenv.
+// The values of private variables cannot be read from the API, so they are
+// written as placeholders without a value, annotated with keep, which imports
+// leave as is. Fill in their value to change them.
+func WriteDotenv(w io.Writer, envVars []*EnvVar) error {
+	for _, ev := range envVars {
+		if ev.Name == nil {
+			continue
+		}
+
+		branch := ""
+		if ev.Branch != nil {
+			branch = *ev.Branch
+		}
+		public := ev.Public != nil && *ev.Public
+
+		visibility := "public"
+		if !public {
+			visibility = "private"
+		}
+		annotation := visibility
+		if branch != "" {
+			annotation += ", branch=" + branch
+		}
+
+		var err error
+		if !public || ev.Value == nil {
+			_, err = fmt.Fprintf(w, "# %s is %s, its value cannot be exported\n# travis: %s, keep\n%s=\n",
+				envVarDisplayName(*ev.Name, branch), visibility, annotation, *ev.Name)
+		} else {
+			_, err = fmt.Fprintf(w, "# travis: %s\n%s=%s\n", annotation, *ev.Name, quoteDotenvValue(*ev.Value))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var dotenvUnquotedValuePattern = regexp.MustCompile(`^[A-Za-z0-9_./:@%+,=-]+$`)
+
+func quoteDotenvValue(v string) string {
+	if dotenvUnquotedValuePattern.MatchString(v) {
+		return v
+	}
+
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+	return `"` + r.Replace(v) + `"`
+}
+
+// envVarDisplayName names an environment variable scoped to a branch NAME@branch
+func envVarDisplayName(name, branch string) string {
+	if branch == "" {
+		return name
+	}
+	return name + "@" + branch
+}
+
+// envVarsAPI binds the environment variable endpoints to a repository
+type envVarsAPI struct {
+	list   func(ctx context.Context) ([]*EnvVar, *http.Response, error)
+	create func(ctx context.Context, envVar *EnvVarBody) (*EnvVar, *http.Response, error)
+	update func(ctx context.Context, id string, envVar *EnvVarBody) (*EnvVar, *http.Response, error)
+	delete func(ctx context.Context, id string) (*http.Response, error)
+}
+
+func (es *EnvVarsService) byRepoId(repoId uint) *envVarsAPI {
+	return &envVarsAPI{
+		list: func(ctx context.Context) ([]*EnvVar, *http.Response, error) {
+			return es.ListByRepoId(ctx, repoId)
+		},
+		create: func(ctx context.Context, envVar *EnvVarBody) (*EnvVar, *http.Response, error) {
+			return es.CreateByRepoId(ctx, repoId, envVar)
+		},
+		update: func(ctx context.Context, id string, envVar *EnvVarBody) (*EnvVar, *http.Response, error) {
+			return es.UpdateByRepoId(ctx, repoId, id, envVar)
+		},
+		delete: func(ctx context.Context, id string) (*http.Response, error) {
+			return es.DeleteByRepoId(ctx, repoId, id)
+		},
+	}
+}
+
+func (es *EnvVarsService) byRepoSlug(repoSlug string) *envVarsAPI {
+	return &envVarsAPI{
+		list: func(ctx context.Context) ([]*EnvVar, *http.Response, error) {
+			return es.ListByRepoSlug(ctx, repoSlug)
+		},
+		create: func(ctx context.Context, envVar *EnvVarBody) (*EnvVar, *http.Response, error) {
+			return es.CreateByRepoSlug(ctx, repoSlug, envVar)
+		},
+		update: func(ctx context.Context, id string, envVar *EnvVarBody) (*EnvVar, *http.Response, error) {
+			return es.UpdateByRepoSlug(ctx, repoSlug, id, envVar)
+		},
+		delete: func(ctx context.Context, id string) (*http.Response, error) {
+			return es.DeleteByRepoSlug(ctx, repoSlug, id)
+		},
+	}
+}
+
+// ImportDotenvByRepoId imports a .env file into the environment variables
+// of given repository id, see ImportDotenvByRepoSlug
+//
+// Travis CI API docs: https://developer.travis-ci.com/resource/env_vars#for_repository
+func (es *EnvVarsService) ImportDotenvByRepoId(ctx context.Context, repoId uint, r io.Reader, opt *ImportDotenvOption) (*EnvVarsImportSummary, *http.Response, error) {
+	return importDotenv(ctx, es.byRepoId(repoId), r, opt)
+}
+
+// ImportDotenvByRepoSlug imports a .env file into the environment variables
+// of given repository slug, see ParseDotenv for the format of the file.
+//
+// Variables are matched by name and branch, and updated instead of being created
+// again. A variable existing only on another branch is left as is and a new one is
+// created, which Prune then deletes. The values of private variables
+// cannot be compared, so existing private variables are always updated,
+// except for the placeholders written by WriteDotenv, which are kept as is
+// and are not deleted by Prune.
+//
+// Travis CI API docs: https://developer.travis-ci.com/resource/env_vars#for_repository
+func (es *EnvVarsService) ImportDotenvByRepoSlug(ctx context.Context, repoSlug string, r io.Reader, opt *ImportDotenvOption) (*EnvVarsImportSummary, *http.Response, error) {
+	return importDotenv(ctx, es.byRepoSlug(repoSlug), r, opt)
+}
+
+// ExportDotenvByRepoId writes the environment variables of given repository id
+// in the .env format, see WriteDotenv
+//
+// Travis CI API docs: https://developer.travis-ci.com/resource/env_vars#for_repository
+func (es *EnvVarsService) ExportDotenvByRepoId(ctx context.Context, repoId uint, w io.Writer) (*http.Response, error) {
+	envVars, resp, err := es.ListByRepoId(ctx, repoId)
+	if err != nil {
+		return resp, err
+	}
+
+	return resp, WriteDotenv(w, envVars)
+}
+
+// ExportDotenvByRepoSlug writes the environment variables of given repository slug
+// in the .env format, see WriteDotenv
+//
+// Travis CI API docs: https://developer.travis-ci.com/resource/env_vars#for_repository
+func (es *EnvVarsService) ExportDotenvByRepoSlug(ctx context.Context, repoSlug string, w io.Writer) (*http.Response, error) {
+	envVars, resp, err := es.ListByRepoSlug(ctx, repoSlug)
+	if err != nil {
+		return resp, err
+	}
+
+	return resp, WriteDotenv(w, envVars)
+}
+
+func importDotenv(ctx context.Context, api *envVarsAPI, r io.Reader, opt *ImportDotenvOption) (*EnvVarsImportSummary, *http.Response, error) {
+	if opt == nil {
+		opt = &ImportDotenvOption{}
+	}
+
+	envVars, err := parseDotenv(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	live, resp, err := api.list(ctx)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	summary := &EnvVarsImportSummary{}
+	matched := map[*EnvVar]bool{}
+
+	for _, v := range envVars {
+		ev := v.EnvVarBody
+		existing := matchEnvVar(live, matched, ev)
+		name := envVarDisplayName(ev.Name, ev.Branch)
+
+		switch {
+		case v.keep:
+			// Placeholders keep the existing variable, and have no value to create one with
+			if existing != nil {
+				summary.Unchanged = append(summary.Unchanged, name)
+			}
+		case existing == nil:
+			if _, resp, err = api.create(ctx, ev); err != nil {
+				return summary, resp, err
+			}
+			summary.Created = append(summary.Created, name)
+		case envVarUnchanged(existing, ev):
+			summary.Unchanged = append(summary.Unchanged, name)
+		default:
+			if _, resp, err = api.update(ctx, *existing.Id, ev); err != nil {
+				return summary, resp, err
+			}
+			summary.Updated = append(summary.Updated, name)
+		}
+	}
+
+	if opt.Prune {
+		for _, ev := range live {
+			if matched[ev] || ev.Id == nil || ev.Name == nil {
+				continue
+			}
+			if resp, err = api.delete(ctx, *ev.Id); err != nil {
+				return summary, resp, err
+			}
+
+			branch := ""
+			if ev.Branch != nil {
+				branch = *ev.Branch
+			}
+			summary.Deleted = append(summary.Deleted, envVarDisplayName(*ev.Name, branch))
+		}
+	}
+
+	return summary, resp, nil
+}
+
+// matchEnvVar returns the live environment variable to update with ev,
+// i.e. the one with the same name scoped to the same branch, and marks it as matched.
+// A variable scoped to another branch is not a match, as updating it would change its scope.
+func matchEnvVar(live []*EnvVar, matched map[*EnvVar]bool, ev *EnvVarBody) *EnvVar {
+	for _, l := range live {
+		if matched[l] || l.Id == nil || l.Name == nil || *l.Name != ev.Name {
+			continue
+		}
+
+		branch := ""
+		if l.Branch != nil {
+			branch = *l.Branch
+		}
+		if branch == ev.Branch {
+			matched[l] = true
+			return l
+		}
+	}
+
+	return nil
+}
+
+func envVarUnchanged(live *EnvVar, ev *EnvVarBody) bool {
+	branch := ""
+	if live.Branch != nil {
+		branch = *live.Branch
+	}
+
+	return ev.Public &&
+		live.Public != nil && *live.Public &&
+		live.Value != nil && *live.Value == ev.Value &&
+		branch == ev.Branch
+}
@@ -0,0 +1,264 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDotenv(t *testing.T) {
+	dotenv := `# Build configuration
+# travis: public
+GO_VERSION=1.14 # inline comment
+
+# travis: private, branch=production
+export API_TOKEN="s3cr3t \"quoted\"\nline"
+SINGLE='literal \n value'
+EMPTY=
+# travis: public branch=develop
+SPACED = spaced value
+# travis: private, keep
+PLACEHOLDER=
+# travis: private, keep
+FILLED=value
+`
+
+	got, err := ParseDotenv(strings.NewReader(dotenv))
+	if err != nil {
+		t.Fatalf("ParseDotenv returned error: %v", err)
+	}
+
+	want := []*EnvVarBody{
+		{Name: "GO_VERSION", Value: "1.14", Public: true},
+		{Name: "API_TOKEN", Value: "s3cr3t \"quoted\"\nline", Branch: "production"},
+		{Name: "SINGLE", Value: `literal \n value`},
+		{Name: "EMPTY", Value: ""},
+		{Name: "SPACED", Value: "spaced value", Public: true, Branch: "develop"},
+		{Name: "FILLED", Value: "value"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseDotenv returned %+v, want %+v", got, want)
+	}
+}
+
+func TestParseDotenv_invalid(t *testing.T) {
+	cases := []string{
+		"NO_EQUALS",
+		"1NAME=value",
+		`UNTERMINATED="value`,
+		`TRAILING="value" garbage`,
+		"# travis: protected\nNAME=value",
+	}
+
+	for i, c := range cases {
+		if _, err := ParseDotenv(strings.NewReader(c)); err == nil {
+			t.Errorf("#%d ParseDotenv returned no error", i)
+		}
+	}
+}
+
+func TestWriteDotenv(t *testing.T) {
+	envVars := []*EnvVar{
+		{Name: String("GO_VERSION"), Value: String("1.14"), Public: Bool(true)},
+		{Name: String("GREETING"), Value: String("hello \"world\""), Public: Bool(true), Branch: String("develop")},
+		{Name: String("API_TOKEN"), Public: Bool(false), Branch: String("production")},
+	}
+
+	var b bytes.Buffer
+	if err := WriteDotenv(&b, envVars); err != nil {
+		t.Fatalf("WriteDotenv returned error: %v", err)
+	}
+
+	want := `# travis: public
+GO_VERSION=1.14
+# travis: public, branch=develop
+GREETING="hello \"world\""
+# API_TOKEN@production is private, its value cannot be exported
+# travis: private, branch=production, keep
+API_TOKEN=
+`
+	if b.String() != want {
+		t.Errorf("WriteDotenv wrote\n%s\nwant\n%s", b.String(), want)
+	}
+
+	// Public variables are read back unchanged, and placeholders are left out
+	parsed, err := ParseDotenv(&b)
+	if err != nil {
+		t.Fatalf("ParseDotenv returned error: %v", err)
+	}
+	if len(parsed) != 2 || parsed[1].Value != `hello "world"` || parsed[1].Branch != "develop" {
+		t.Errorf("ParseDotenv returned %+v", parsed)
+	}
+}
+
+func TestEnvVarsService_ImportDotenvByRepoSlug(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var requests []string
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/env_vars", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			var body EnvVarBody
+			json.NewDecoder(r.Body).Decode(&body)
+			requests = append(requests, "POST "+body.Name)
+			fmt.Fprint(w, `{"id":"new"}`)
+			return
+		}
+		fmt.Fprint(w, `{"env_vars":[
+		  {"id":"1","name":"UNCHANGED","value":"same","public":true},
+		  {"id":"2","name":"TOKEN","public":false,"branch":"develop"},
+		  {"id":"3","name":"TOKEN","public":false,"branch":"production"},
+		  {"id":"4","name":"STALE","public":false}
+		]}`)
+	})
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/env_var/", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:])
+		fmt.Fprint(w, `{}`)
+	})
+
+	dotenv := `# travis: public
+UNCHANGED=same
+# travis: branch=production
+TOKEN=new-token
+CREATED=value
+`
+
+	summary, _, err := client.EnvVars.ImportDotenvByRepoSlug(context.Background(), testRepoSlug, strings.NewReader(dotenv), &ImportDotenvOption{Prune: true})
+
+	if err != nil {
+		t.Fatalf("EnvVars.ImportDotenvByRepoSlug returned error: %v", err)
+	}
+
+	want := &EnvVarsImportSummary{
+		Created:   []string{"CREATED"},
+		Updated:   []string{"TOKEN@production"},
+		Unchanged: []string{"UNCHANGED"},
+		Deleted:   []string{"TOKEN@develop", "STALE"},
+	}
+	if !reflect.DeepEqual(summary, want) {
+		t.Errorf("EnvVars.ImportDotenvByRepoSlug returned %+v, want %+v", summary, want)
+	}
+
+	wantRequests := []string{"PATCH 3", "POST CREATED", "DELETE 2", "DELETE 4"}
+	if !reflect.DeepEqual(requests, wantRequests) {
+		t.Errorf("EnvVars.ImportDotenvByRepoSlug made requests %v, want %v", requests, wantRequests)
+	}
+}
+
+func TestEnvVarsService_ExportDotenvByRepoId(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc(fmt.Sprintf("/repo/%d/env_vars", testRepoId), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, `{"env_vars":[{"id":"1","name":"FOO","value":"bar","public":true}]}`)
+	})
+
+	var b bytes.Buffer
+	_, err := client.EnvVars.ExportDotenvByRepoId(context.Background(), testRepoId, &b)
+
+	if err != nil {
+		t.Fatalf("EnvVars.ExportDotenvByRepoId returned error: %v", err)
+	}
+
+	if want := "# travis: public\nFOO=bar\n"; b.String() != want {
+		t.Errorf("EnvVars.ExportDotenvByRepoId wrote %q, want %q", b.String(), want)
+	}
+}
+
+func TestEnvVarsService_ImportDotenvByRepoSlug_otherBranch(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var requests []string
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/env_vars", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			var body EnvVarBody
+			json.NewDecoder(r.Body).Decode(&body)
+			requests = append(requests, "POST "+envVarDisplayName(body.Name, body.Branch))
+			fmt.Fprint(w, `{"id":"new"}`)
+			return
+		}
+		fmt.Fprint(w, `{"env_vars":[
+		  {"id":"1","name":"TOKEN","public":false,"branch":"develop"},
+		  {"id":"2","name":"URL","value":"same","public":true,"branch":"develop"}
+		]}`)
+	})
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/env_var/", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:])
+		fmt.Fprint(w, `{}`)
+	})
+
+	dotenv := `# travis: branch=production
+TOKEN=new-token
+# travis: public
+URL=same
+`
+
+	summary, _, err := client.EnvVars.ImportDotenvByRepoSlug(context.Background(), testRepoSlug, strings.NewReader(dotenv), nil)
+
+	if err != nil {
+		t.Fatalf("EnvVars.ImportDotenvByRepoSlug returned error: %v", err)
+	}
+
+	want := &EnvVarsImportSummary{Created: []string{"TOKEN@production", "URL"}}
+	if !reflect.DeepEqual(summary, want) {
+		t.Errorf("EnvVars.ImportDotenvByRepoSlug returned %+v, want %+v", summary, want)
+	}
+
+	wantRequests := []string{"POST TOKEN@production", "POST URL"}
+	if !reflect.DeepEqual(requests, wantRequests) {
+		t.Errorf("EnvVars.ImportDotenvByRepoSlug made requests %v, want %v", requests, wantRequests)
+	}
+}
+
+func TestEnvVarsService_ImportDotenvByRepoSlug_roundTrip(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var requests []string
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/env_vars", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			requests = append(requests, r.Method)
+			fmt.Fprint(w, `{}`)
+			return
+		}
+		fmt.Fprint(w, `{"env_vars":[
+		  {"id":"1","name":"GO_VERSION","value":"1.14","public":true},
+		  {"id":"2","name":"API_TOKEN","public":false},
+		  {"id":"3","name":"DEPLOY_KEY","public":false,"branch":"production"}
+		]}`)
+	})
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/env_var/", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:])
+		fmt.Fprint(w, `{}`)
+	})
+
+	var b bytes.Buffer
+	if _, err := client.EnvVars.ExportDotenvByRepoSlug(context.Background(), testRepoSlug, &b); err != nil {
+		t.Fatalf("EnvVars.ExportDotenvByRepoSlug returned error: %v", err)
+	}
+
+	summary, _, err := client.EnvVars.ImportDotenvByRepoSlug(context.Background(), testRepoSlug, &b, &ImportDotenvOption{Prune: true})
+	if err != nil {
+		t.Fatalf("EnvVars.ImportDotenvByRepoSlug returned error: %v", err)
+	}
+
+	want := &EnvVarsImportSummary{Unchanged: []string{"GO_VERSION", "API_TOKEN", "DEPLOY_KEY@production"}}
+	if !reflect.DeepEqual(summary, want) {
+		t.Errorf("EnvVars.ImportDotenvByRepoSlug returned %+v, want %+v", summary, want)
+	}
+	if len(requests) != 0 {
+		t.Errorf("EnvVars.ImportDotenvByRepoSlug made requests %v, want none", requests)
+	}
+}
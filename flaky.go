@@ -0,0 +1,223 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// FlakyOption specifies the optional parameters of AnalyzeFlakyJobs
+type FlakyOption struct {
+	// Only analyze the builds of this branch
+	Branch string
+	// How many recent builds to analyze. Defaults to 200.
+	MaxBuilds int
+}
+
+// FlakyEvidenceKind is the kind of a change of outcome of a job
+type FlakyEvidenceKind string
+
+const (
+	// FlakyEvidenceRerunPassed is a job which failed, then passed when run again on the same commit
+	FlakyEvidenceRerunPassed FlakyEvidenceKind = "rerun_passed"
+	// FlakyEvidenceRerunFailed is a job which passed, then failed when run again on the same commit
+	FlakyEvidenceRerunFailed FlakyEvidenceKind = "rerun_failed"
+)
+
+// FlakyEvidence is a change of outcome of a job between two runs on the same commit
+type FlakyEvidence struct {
+	Kind FlakyEvidenceKind `json:"kind"`
+	// The sha of the commit
+	Sha string `json:"sha"`
+	// The first job, its state and the URL of its log
+	FromJobId uint   `json:"from_job_id"`
+	FromState string `json:"from_state"`
+	FromUrl   string `json:"from_url"`
+	// The job run after it, its state and the URL of its log
+	ToJobId uint   `json:"to_job_id"`
+	ToState string `json:"to_state"`
+	ToUrl   string `json:"to_url"`
+}
+
+// FlakyJob is the flakiness of an entry of the build matrix
+type FlakyJob struct {
+	// The matrix entry, the suffix of the job numbers, e.g. 3 for job 25.3
+	MatrixKey string `json:"matrix_key"`
+	// How many commits the entry ran on more than once
+	Commits int `json:"commits"`
+	// How many of these commits had different outcomes
+	FlakyCommits int `json:"flaky_commits"`
+	// How many times the outcome changed between consecutive runs
+	Flips int `json:"flips"`
+	// FlakyCommits / Commits, from 0 (stable) to 1 (always flaky)
+	Score float64 `json:"score"`
+	// The changes of outcome
+	Evidence []*FlakyEvidence `json:"evidence"`
+}
+
+// FlakyReport is the result of AnalyzeFlakyJobs
+type FlakyReport struct {
+	RepoSlug string `json:"repo_slug"`
+	// How many builds were analyzed
+	Builds int `json:"builds"`
+	// The entries of the build matrix which ran more than once on a commit,
+	// most flaky first
+	Jobs []*FlakyJob `json:"jobs"`
+}
+
+// AnalyzeFlakyJobs walks the build history of a repository and finds the jobs
+// whose outcome differs between runs on the same commit, e.g. a job which failed
+// and passed when its build was triggered again, or a commit built both
+// for a push and for a pull request with different outcomes.
+//
+// Jobs are compared by their entry in the build matrix, given by the suffix
+// of their number. Only passed, failed and errored jobs are compared.
+// Restarting a build reuses its jobs, so only the outcome of the last run
+// of a restarted build is known.
+func (c *Client) AnalyzeFlakyJobs(ctx context.Context, repoSlug string, opt *FlakyOption) (*FlakyReport, *http.Response, error) {
+	maxBuilds := 200
+	listOpt := BuildsByRepoOption{}
+	if opt != nil {
+		if opt.MaxBuilds > 0 {
+			maxBuilds = opt.MaxBuilds
+		}
+		if opt.Branch != "" {
+			listOpt.BranchName = []string{opt.Branch}
+		}
+	}
+	if maxBuilds < 100 {
+		listOpt.Limit = maxBuilds
+	}
+
+	report := &FlakyReport{RepoSlug: repoSlug}
+	bySha := map[string][]*Build{}
+
+	resp, err := c.Builds.walkByRepoSlug(ctx, repoSlug, listOpt, func(b *Build) bool {
+		report.Builds++
+		if b.Id != nil && b.Commit != nil && b.Commit.Sha != nil && b.State != nil && buildStateFinished(*b.State) {
+			bySha[*b.Commit.Sha] = append(bySha[*b.Commit.Sha], b)
+		}
+		return report.Builds < maxBuilds
+	})
+	if err != nil {
+		return nil, resp, err
+	}
+
+	shas := make([]string, 0, len(bySha))
+	for sha, builds := range bySha {
+		if len(builds) > 1 {
+			shas = append(shas, sha)
+		}
+	}
+	sort.Strings(shas)
+
+	jobs := map[string]*FlakyJob{}
+	webURL := c.webURL()
+
+	for _, sha := range shas {
+		builds := bySha[sha]
+		// Oldest first
+		sort.Slice(builds, func(i, j int) bool { return *builds[i].Id < *builds[j].Id })
+
+		runs := map[string][]*Job{}
+		var keys []string
+		for _, b := range builds {
+			var buildJobs []*Job
+			buildJobs, resp, err = c.Jobs.ListByBuild(ctx, *b.Id)
+			if err != nil {
+				return nil, resp, err
+			}
+
+			for _, j := range buildJobs {
+				if j.Id == nil || j.Number == nil || j.State == nil || !flakyComparable(*j.State) {
+					continue
+				}
+				key := matrixKey(*j.Number)
+				if runs[key] == nil {
+					keys = append(keys, key)
+				}
+				runs[key] = append(runs[key], j)
+			}
+		}
+
+		for _, key := range keys {
+			if len(runs[key]) < 2 {
+				continue
+			}
+
+			fj, ok := jobs[key]
+			if !ok {
+				fj = &FlakyJob{MatrixKey: key}
+				jobs[key] = fj
+			}
+			fj.Commits++
+
+			flips := 0
+			for i := 1; i < len(runs[key]); i++ {
+				from, to := runs[key][i-1], runs[key][i]
+				if (*from.State == BuildStatePassed) == (*to.State == BuildStatePassed) {
+					continue
+				}
+				flips++
+
+				kind := FlakyEvidenceRerunPassed
+				if *from.State == BuildStatePassed {
+					kind = FlakyEvidenceRerunFailed
+				}
+				fj.Evidence = append(fj.Evidence, &FlakyEvidence{
+					Kind:      kind,
+					Sha:       sha,
+					FromJobId: *from.Id,
+					FromState: *from.State,
+					FromUrl:   fmt.Sprintf("%s%s/jobs/%d", webURL, repoSlug, *from.Id),
+					ToJobId:   *to.Id,
+					ToState:   *to.State,
+					ToUrl:     fmt.Sprintf("%s%s/jobs/%d", webURL, repoSlug, *to.Id),
+				})
+			}
+			if flips > 0 {
+				fj.FlakyCommits++
+				fj.Flips += flips
+			}
+		}
+	}
+
+	for _, fj := range jobs {
+		fj.Score = float64(fj.FlakyCommits) / float64(fj.Commits)
+		report.Jobs = append(report.Jobs, fj)
+	}
+	sort.Slice(report.Jobs, func(i, j int) bool {
+		a, b := report.Jobs[i], report.Jobs[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Flips != b.Flips {
+			return a.Flips > b.Flips
+		}
+		return a.MatrixKey < b.MatrixKey
+	})
+
+	return report, resp, nil
+}
+
+// flakyComparable tells if the outcome of a job in the given state
+// tells whether it is flaky, unlike canceled or running jobs
+func flakyComparable(state string) bool {
+	return state == BuildStatePassed || state == BuildStateFailed || state == BuildStateErrored
+}
+
+// matrixKey returns the entry of the build matrix of a job number,
+// e.g. 3 for 25.3
+func matrixKey(number string) string {
+	if i := strings.LastIndex(number, "."); i >= 0 {
+		return number[i+1:]
+	}
+	return number
+}
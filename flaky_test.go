@@ -0,0 +1,90 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestClient_AnalyzeFlakyJobs(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/builds", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		testFormValues(t, r, values{"branch.name": "master", "limit": "5"})
+		fmt.Fprint(w, `{"builds":[
+		  {"id":4,"state":"passed","commit":{"sha":"aaa"}},
+		  {"id":3,"state":"started","commit":{"sha":"aaa"}},
+		  {"id":2,"state":"failed","commit":{"sha":"aaa"}},
+		  {"id":1,"state":"passed","commit":{"sha":"bbb"}},
+		  {"id":0,"state":"passed","commit":{"sha":"bbb"}}
+		]}`)
+	})
+
+	jobs := map[int]string{
+		4: `[{"id":41,"number":"4.1","state":"passed"},{"id":42,"number":"4.2","state":"passed"}]`,
+		2: `[{"id":21,"number":"2.1","state":"failed"},{"id":22,"number":"2.2","state":"passed"}]`,
+		1: `[{"id":11,"number":"1.1","state":"passed"},{"id":12,"number":"1.2","state":"canceled"}]`,
+		0: `[{"id":1,"number":"0.1","state":"passed"},{"id":2,"number":"0.2","state":"passed"}]`,
+	}
+	for id, body := range jobs {
+		body := body
+		mux.HandleFunc(fmt.Sprintf("/build/%d/jobs", id), func(w http.ResponseWriter, r *http.Request) {
+			testMethod(t, r, http.MethodGet)
+			fmt.Fprintf(w, `{"jobs":%s}`, body)
+		})
+	}
+
+	report, _, err := client.AnalyzeFlakyJobs(context.Background(), testRepoSlug, &FlakyOption{Branch: "master", MaxBuilds: 5})
+
+	if err != nil {
+		t.Fatalf("Client.AnalyzeFlakyJobs returned error: %v", err)
+	}
+
+	web := client.webURL() + testRepoSlug
+	want := &FlakyReport{
+		RepoSlug: testRepoSlug,
+		Builds:   5,
+		Jobs: []*FlakyJob{
+			{
+				MatrixKey:    "1",
+				Commits:      2,
+				FlakyCommits: 1,
+				Flips:        1,
+				Score:        0.5,
+				Evidence: []*FlakyEvidence{{
+					Kind:      FlakyEvidenceRerunPassed,
+					Sha:       "aaa",
+					FromJobId: 21,
+					FromState: "failed",
+					FromUrl:   web + "/jobs/21",
+					ToJobId:   41,
+					ToState:   "passed",
+					ToUrl:     web + "/jobs/41",
+				}},
+			},
+			{MatrixKey: "2", Commits: 1},
+		},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("Client.AnalyzeFlakyJobs returned %+v, want %+v", report, want)
+	}
+}
+
+func TestMatrixKey(t *testing.T) {
+	cases := map[string]string{"25.3": "3", "25.10": "10", "25": "25"}
+
+	for number, want := range cases {
+		if got := matrixKey(number); got != want {
+			t.Errorf("matrixKey(%q) returned %q, want %q", number, got, want)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Home describes the resources and actions served by a Travis CI API
+//
+// Travis CI API docs: https://developer.travis-ci.com/resource/home
+type Home struct {
+	// The resources served by the API, keyed by resource type
+	Resources map[string]*ResourceDescription `json:"resources,omitempty"`
+	*Metadata
+
+	// endpoints are the actions served by the API, compiled once from Resources
+	endpointsOnce sync.Once
+	endpoints     []*endpoint
+}
+
+// endpoint is an action served by the API, matching the paths of its URI template
+type endpoint struct {
+	method  string
+	pattern *regexp.Regexp
+}
+
+// ResourceDescription describes a resource served by a Travis CI API
+type ResourceDescription struct {
+	// The actions available on the resource, keyed by action name (e.g. find, for_owner)
+	Actions map[string][]*ActionTemplate `json:"actions,omitempty"`
+	// The attributes of the resource
+	Attributes []string `json:"attributes,omitempty"`
+	// The attributes lists of the resource can be sorted by
+	SortableBy []string `json:"sortable_by,omitempty"`
+	// The order lists of the resource are sorted in by default
+	DefaultSort *string `json:"default_sort,omitempty"`
+	*Metadata
+}
+
+// ActionTemplate describes a request performing an action on a resource
+type ActionTemplate struct {
+	// The HTTP method of the request
+	RequestMethod *string `json:"request_method,omitempty"`
+	// The URI template of the request, e.g. /build/{build.id}{?include}
+	UriTemplate *string `json:"uri_template,omitempty"`
+	*Metadata
+}
+
+// UnsupportedEndpointError is returned when a request is made to an
+// endpoint the discovered API does not serve, e.g. because a Travis CI
+// Enterprise installation runs an older version of the API
+type UnsupportedEndpointError struct {
+	// The HTTP method of the request
+	Method string
+	// The path of the request relative to the BaseURL of the client
+	Path string
+}
+
+func (e *UnsupportedEndpointError) Error() string {
+	return fmt.Sprintf("travis: %s %s is not supported by the API", e.Method, e.Path)
+}
+
+// Discover fetches the description of the resources and actions served by
+// the API. Once discovered, requests to endpoints the API does not serve
+// fail with an UnsupportedEndpointError before they are sent.
+//
+// Travis CI API docs: https://developer.travis-ci.com/resource/home
+func (c *Client) Discover(ctx context.Context) (*Home, *http.Response, error) {
+	req, err := c.NewRequest(http.MethodGet, "", nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var home Home
+	resp, err := c.Do(ctx, req, &home)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	// Compile the URI templates once, rather than on every request
+	home.compileEndpoints()
+
+	c.homeMu.Lock()
+	c.home = &home
+	c.homeMu.Unlock()
+
+	return &home, resp, err
+}
+
+// checkSupported returns an UnsupportedEndpointError if the API
+// has been discovered and does not serve the given endpoint
+func (c *Client) checkSupported(method, path string) error {
+	c.homeMu.RLock()
+	home := c.home
+	c.homeMu.RUnlock()
+
+	if home == nil || home.supports(method, path) {
+		return nil
+	}
+
+	return &UnsupportedEndpointError{Method: method, Path: path}
+}
+
+// Supports tells if the API serves the given action on the given resource,
+// e.g. Supports("build", "restart")
+func (h *Home) Supports(resource, action string) bool {
+	r, ok := h.Resources[resource]
+	if !ok {
+		return false
+	}
+
+	return len(r.Actions[action]) > 0
+}
+
+// supports tells if any action served by the API
+// matches the given method and escaped path
+func (h *Home) supports(method, path string) bool {
+	// The home and the server configuration are not resources
+	if path == "/" || path == "/config" {
+		return true
+	}
+
+	for _, e := range h.compileEndpoints() {
+		if e.method == method && e.pattern.MatchString(path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compileEndpoints compiles the URI templates of the actions served by the API
+// the first time it is called, and returns them
+func (h *Home) compileEndpoints() []*endpoint {
+	h.endpointsOnce.Do(func() {
+		for _, r := range h.Resources {
+			for _, templates := range r.Actions {
+				for _, t := range templates {
+					if t.RequestMethod == nil || t.UriTemplate == nil {
+						continue
+					}
+					h.endpoints = append(h.endpoints, &endpoint{
+						method:  *t.RequestMethod,
+						pattern: uriTemplatePattern(*t.UriTemplate),
+					})
+				}
+			}
+		}
+	})
+
+	return h.endpoints
+}
+
+var uriTemplateExpression = regexp.MustCompile(`\{[^}]*\}`)
+
+// uriTemplatePattern converts a URI template into a pattern matching
+// its paths. Path variables match a single path segment and query
+// expressions (e.g. {?include}) are ignored.
+func uriTemplatePattern(template string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+
+	last := 0
+	for _, loc := range uriTemplateExpression.FindAllStringIndex(template, -1) {
+		b.WriteString(regexp.QuoteMeta(template[last:loc[0]]))
+		if e := template[loc[0]+1]; e != '?' && e != '&' {
+			b.WriteString("[^/]+")
+		}
+		last = loc[1]
+	}
+
+	b.WriteString(regexp.QuoteMeta(template[last:]))
+	b.WriteString("$")
+
+	return regexp.MustCompile(b.String())
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+const testHome = `{
+  "@type": "home",
+  "@href": "/",
+  "resources": {
+    "build": {
+      "@type": "resource",
+      "actions": {
+        "find": [{"@type": "template", "request_method": "GET", "uri_template": "/build/{build.id}{?include}"}],
+        "cancel": [{"@type": "template", "request_method": "POST", "uri_template": "/build/{build.id}/cancel"}]
+      },
+      "attributes": ["id", "number", "state"]
+    },
+    "builds": {
+      "@type": "resource",
+      "actions": {
+        "find": [
+          {"@type": "template", "request_method": "GET", "uri_template": "/repo/{repository.id}/builds{?branch.name,include,limit,offset,sort_by}"},
+          {"@type": "template", "request_method": "GET", "uri_template": "/repo/{repository.slug}/builds{?branch.name,include,limit,offset,sort_by}"}
+        ]
+      },
+      "sortable_by": ["id", "started_at", "finished_at"],
+      "default_sort": "id:desc"
+    }
+  }
+}`
+
+func TestClient_Discover(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, testHome)
+	})
+
+	home, _, err := client.Discover(context.Background())
+
+	if err != nil {
+		t.Fatalf("Client.Discover returned error: %v", err)
+	}
+
+	want := &ResourceDescription{
+		SortableBy:  []string{"id", "started_at", "finished_at"},
+		DefaultSort: String("id:desc"),
+		Actions: map[string][]*ActionTemplate{
+			"find": {
+				{RequestMethod: String(http.MethodGet), UriTemplate: String("/repo/{repository.id}/builds{?branch.name,include,limit,offset,sort_by}"), Metadata: &Metadata{Type: String("template")}},
+				{RequestMethod: String(http.MethodGet), UriTemplate: String("/repo/{repository.slug}/builds{?branch.name,include,limit,offset,sort_by}"), Metadata: &Metadata{Type: String("template")}},
+			},
+		},
+		Metadata: &Metadata{Type: String("resource")},
+	}
+	if got := home.Resources["builds"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("Client.Discover returned %+v, want %+v", got, want)
+	}
+}
+
+func TestHome_Supports(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testHome)
+	})
+
+	home, _, err := client.Discover(context.Background())
+	if err != nil {
+		t.Fatalf("Client.Discover returned error: %v", err)
+	}
+
+	cases := []struct {
+		resource string
+		action   string
+		want     bool
+	}{
+		{"build", "find", true},
+		{"build", "cancel", true},
+		{"build", "restart", false},
+		{"job", "find", false},
+	}
+
+	for i, c := range cases {
+		if got := home.Supports(c.resource, c.action); got != c.want {
+			t.Errorf("#%d Home.Supports(%q, %q) returned %v, want %v", i, c.resource, c.action, got, c.want)
+		}
+	}
+}
+
+func TestClient_Discover_rejectsUnsupportedEndpoints(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testHome)
+	})
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/builds", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"builds": []}`)
+	})
+	restarts := 0
+	mux.HandleFunc(fmt.Sprintf("/build/%d/restart", testBuildId), func(w http.ResponseWriter, r *http.Request) {
+		restarts++
+		fmt.Fprint(w, `{"build":{"id":1}}`)
+	})
+
+	// Requests are not checked until the API is discovered
+	if _, _, err := client.Builds.Restart(context.Background(), testBuildId); err != nil {
+		t.Fatalf("Builds.Restart returned error: %v", err)
+	}
+
+	if _, _, err := client.Discover(context.Background()); err != nil {
+		t.Fatalf("Client.Discover returned error: %v", err)
+	}
+
+	opt := BuildsByRepoOption{Limit: 5}
+	if _, _, err := client.Builds.ListByRepoSlug(context.Background(), testRepoSlug, &opt); err != nil {
+		t.Errorf("Builds.ListByRepoSlug returned error: %v", err)
+	}
+
+	_, _, err := client.Builds.Restart(context.Background(), testBuildId)
+	want := &UnsupportedEndpointError{Method: http.MethodPost, Path: fmt.Sprintf("/build/%d/restart", testBuildId)}
+	if !reflect.DeepEqual(err, want) {
+		t.Errorf("Builds.Restart returned error %v, want %v", err, want)
+	}
+	if restarts != 1 {
+		t.Errorf("Builds.Restart made %d requests, want 1", restarts)
+	}
+}
+
+func TestUriTemplatePattern(t *testing.T) {
+	cases := []struct {
+		template string
+		path     string
+		want     bool
+	}{
+		{"/build/{build.id}{?include}", "/build/1", true},
+		{"/build/{build.id}{?include}", "/build/1/jobs", false},
+		{"/repo/{repository.slug}/builds{?limit}", "/repo/shuheiktgw%2Fgo-travis/builds", true},
+		{"/repo/{repository.slug}/builds{?limit}", "/repo/shuheiktgw/go-travis/builds", false},
+		{"/owner/{owner.login}/active", "/owner/shuheiktgw/active", true},
+	}
+
+	for i, c := range cases {
+		if got := uriTemplatePattern(c.template).MatchString(c.path); got != c.want {
+			t.Errorf("#%d uriTemplatePattern(%q) matching %q returned %v, want %v", i, c.template, c.path, got, c.want)
+		}
+	}
+}
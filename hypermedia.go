@@ -0,0 +1,259 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ErrNoHref is returned when a resource without a @href is refreshed or expanded
+var ErrNoHref = errors.New("travis: the resource has no @href to follow")
+
+// Follow fetches the resource the provided @href links to,
+// and decodes it into the value pointed to by v.
+// The @href is resolved relative to the BaseURL of the Client.
+//
+// Travis CI API docs: https://developer.travis-ci.com/hypermedia
+func (c *Client) Follow(ctx context.Context, href string, v interface{}) (*http.Response, error) {
+	req, err := c.NewRequest(http.MethodGet, strings.TrimPrefix(href, "/"), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Do(ctx, req, v)
+}
+
+// follow fetches the resource described by m into v
+func follow(ctx context.Context, c *Client, m *Metadata, v interface{}) (*http.Response, error) {
+	if m == nil || m.Href == nil {
+		return nil, ErrNoHref
+	}
+
+	return c.Follow(ctx, *m.Href, v)
+}
+
+// Refresh fetches the build again from its @href and replaces
+// it with the standard representation returned by the API
+func (b *Build) Refresh(ctx context.Context, client *Client) (*http.Response, error) {
+	var fresh Build
+	resp, err := follow(ctx, client, b.Metadata, &fresh)
+	if err != nil {
+		return resp, err
+	}
+
+	*b = fresh
+	return resp, err
+}
+
+// Expand refreshes the build unless it is already in a standard representation
+func (b *Build) Expand(ctx context.Context, client *Client) (*http.Response, error) {
+	if b.Metadata.IsStandard() {
+		return nil, nil
+	}
+
+	return b.Refresh(ctx, client)
+}
+
+// Refresh fetches the branch again from its @href and replaces
+// it with the standard representation returned by the API
+func (b *Branch) Refresh(ctx context.Context, client *Client) (*http.Response, error) {
+	var fresh Branch
+	resp, err := follow(ctx, client, b.Metadata, &fresh)
+	if err != nil {
+		return resp, err
+	}
+
+	*b = fresh
+	return resp, err
+}
+
+// Expand refreshes the branch unless it is already in a standard representation
+func (b *Branch) Expand(ctx context.Context, client *Client) (*http.Response, error) {
+	if b.Metadata.IsStandard() {
+		return nil, nil
+	}
+
+	return b.Refresh(ctx, client)
+}
+
+// Refresh fetches the cron again from its @href and replaces
+// it with the standard representation returned by the API
+func (c *Cron) Refresh(ctx context.Context, client *Client) (*http.Response, error) {
+	var fresh Cron
+	resp, err := follow(ctx, client, c.Metadata, &fresh)
+	if err != nil {
+		return resp, err
+	}
+
+	*c = fresh
+	return resp, err
+}
+
+// Expand refreshes the cron unless it is already in a standard representation
+func (c *Cron) Expand(ctx context.Context, client *Client) (*http.Response, error) {
+	if c.Metadata.IsStandard() {
+		return nil, nil
+	}
+
+	return c.Refresh(ctx, client)
+}
+
+// Refresh fetches the installation again from its @href and replaces
+// it with the standard representation returned by the API
+func (i *Installation) Refresh(ctx context.Context, client *Client) (*http.Response, error) {
+	var fresh Installation
+	resp, err := follow(ctx, client, i.Metadata, &fresh)
+	if err != nil {
+		return resp, err
+	}
+
+	*i = fresh
+	return resp, err
+}
+
+// Expand refreshes the installation unless it is already in a standard representation
+func (i *Installation) Expand(ctx context.Context, client *Client) (*http.Response, error) {
+	if i.Metadata.IsStandard() {
+		return nil, nil
+	}
+
+	return i.Refresh(ctx, client)
+}
+
+// Refresh fetches the job again from its @href and replaces
+// it with the standard representation returned by the API
+func (j *Job) Refresh(ctx context.Context, client *Client) (*http.Response, error) {
+	var fresh Job
+	resp, err := follow(ctx, client, j.Metadata, &fresh)
+	if err != nil {
+		return resp, err
+	}
+
+	*j = fresh
+	return resp, err
+}
+
+// Expand refreshes the job unless it is already in a standard representation
+func (j *Job) Expand(ctx context.Context, client *Client) (*http.Response, error) {
+	if j.Metadata.IsStandard() {
+		return nil, nil
+	}
+
+	return j.Refresh(ctx, client)
+}
+
+// Refresh fetches the organization again from its @href and replaces
+// it with the standard representation returned by the API
+func (o *Organization) Refresh(ctx context.Context, client *Client) (*http.Response, error) {
+	var fresh Organization
+	resp, err := follow(ctx, client, o.Metadata, &fresh)
+	if err != nil {
+		return resp, err
+	}
+
+	*o = fresh
+	return resp, err
+}
+
+// Expand refreshes the organization unless it is already in a standard representation
+func (o *Organization) Expand(ctx context.Context, client *Client) (*http.Response, error) {
+	if o.Metadata.IsStandard() {
+		return nil, nil
+	}
+
+	return o.Refresh(ctx, client)
+}
+
+// Refresh fetches the owner again from its @href and replaces
+// it with the standard representation returned by the API
+func (o *Owner) Refresh(ctx context.Context, client *Client) (*http.Response, error) {
+	var fresh Owner
+	resp, err := follow(ctx, client, o.Metadata, &fresh)
+	if err != nil {
+		return resp, err
+	}
+
+	*o = fresh
+	return resp, err
+}
+
+// Expand refreshes the owner unless it is already in a standard representation
+func (o *Owner) Expand(ctx context.Context, client *Client) (*http.Response, error) {
+	if o.Metadata.IsStandard() {
+		return nil, nil
+	}
+
+	return o.Refresh(ctx, client)
+}
+
+// Refresh fetches the repository again from its @href and replaces
+// it with the standard representation returned by the API
+func (r *Repository) Refresh(ctx context.Context, client *Client) (*http.Response, error) {
+	var fresh Repository
+	resp, err := follow(ctx, client, r.Metadata, &fresh)
+	if err != nil {
+		return resp, err
+	}
+
+	*r = fresh
+	return resp, err
+}
+
+// Expand refreshes the repository unless it is already in a standard representation
+func (r *Repository) Expand(ctx context.Context, client *Client) (*http.Response, error) {
+	if r.Metadata.IsStandard() {
+		return nil, nil
+	}
+
+	return r.Refresh(ctx, client)
+}
+
+// Refresh fetches the request again from its @href and replaces
+// it with the standard representation returned by the API
+func (r *Request) Refresh(ctx context.Context, client *Client) (*http.Response, error) {
+	var fresh Request
+	resp, err := follow(ctx, client, r.Metadata, &fresh)
+	if err != nil {
+		return resp, err
+	}
+
+	*r = fresh
+	return resp, err
+}
+
+// Expand refreshes the request unless it is already in a standard representation
+func (r *Request) Expand(ctx context.Context, client *Client) (*http.Response, error) {
+	if r.Metadata.IsStandard() {
+		return nil, nil
+	}
+
+	return r.Refresh(ctx, client)
+}
+
+// Refresh fetches the user again from its @href and replaces
+// it with the standard representation returned by the API
+func (u *User) Refresh(ctx context.Context, client *Client) (*http.Response, error) {
+	var fresh User
+	resp, err := follow(ctx, client, u.Metadata, &fresh)
+	if err != nil {
+		return resp, err
+	}
+
+	*u = fresh
+	return resp, err
+}
+
+// Expand refreshes the user unless it is already in a standard representation
+func (u *User) Expand(ctx context.Context, client *Client) (*http.Response, error) {
+	if u.Metadata.IsStandard() {
+		return nil, nil
+	}
+
+	return u.Refresh(ctx, client)
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestClient_Follow(t *testing.T) {
+	client, mux, serverURL, teardown := setup()
+	defer teardown()
+
+	// Enterprise installations serve the API under a path prefix
+	client.BaseURL, _ = url.Parse(serverURL + "/api/")
+
+	mux.HandleFunc(fmt.Sprintf("/api/build/%d", testBuildId), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		testHeader(t, r, "Travis-API-Version", apiVersion3)
+		fmt.Fprint(w, `{"id":1,"number":"1","state":"created","duration":10}`)
+	})
+
+	var build Build
+	_, err := client.Follow(context.Background(), fmt.Sprintf("/build/%d", testBuildId), &build)
+
+	if err != nil {
+		t.Errorf("Client.Follow returned error: %v", err)
+	}
+
+	want := Build{Id: Uint(testBuildId), Number: String("1"), State: String(BuildStateCreated), Duration: Int64(10)}
+	if !reflect.DeepEqual(build, want) {
+		t.Errorf("Client.Follow returned %+v, want %+v", build, want)
+	}
+}
+
+func TestBuild_Expand(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	requests := 0
+	mux.HandleFunc(fmt.Sprintf("/build/%d", testBuildId), func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, `{"@type":"build","@href":"/build/1","@representation":"standard","id":1,"number":"1","state":"passed"}`)
+	})
+
+	build := &Build{
+		Id:       Uint(testBuildId),
+		State:    String(BuildStateCreated),
+		Metadata: &Metadata{Type: String("build"), Href: String("/build/1"), Representation: String(minimalRepresentation)},
+	}
+
+	if _, err := build.Expand(context.Background(), client); err != nil {
+		t.Fatalf("Build.Expand returned error: %v", err)
+	}
+
+	want := &Build{
+		Id:       Uint(testBuildId),
+		Number:   String("1"),
+		State:    String(BuildStatePassed),
+		Metadata: &Metadata{Type: String("build"), Href: String("/build/1"), Representation: String(standardRepresentation)},
+	}
+	if !reflect.DeepEqual(build, want) {
+		t.Errorf("Build.Expand returned %+v, want %+v", build, want)
+	}
+
+	// Already standard, so nothing should be fetched
+	if _, err := build.Expand(context.Background(), client); err != nil {
+		t.Fatalf("Build.Expand returned error: %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("Build.Expand made %d requests, want 1", requests)
+	}
+
+	if _, err := build.Refresh(context.Background(), client); err != nil {
+		t.Fatalf("Build.Refresh returned error: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Build.Refresh made %d requests, want 2", requests)
+	}
+}
+
+func TestRepository_Expand_withoutHref(t *testing.T) {
+	client, _, _, teardown := setup()
+	defer teardown()
+
+	repository := &Repository{Id: Uint(testRepoId)}
+
+	if _, err := repository.Expand(context.Background(), client); err != ErrNoHref {
+		t.Errorf("Repository.Expand returned error %v, want %v", err, ErrNoHref)
+	}
+}
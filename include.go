@@ -0,0 +1,438 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// eagerLoadable maps each resource type to the attributes which can be
+// eager loaded on it, and each attribute to the resource type it refers to.
+// Attributes which do not refer to another resource map to an empty string.
+//
+// Travis CI API docs: https://developer.travis-ci.com/eager-loading
+var eagerLoadable = map[string]map[string]string{
+	"active": {
+		"builds": "build",
+	},
+	"beta_migration_request": {
+		"organizations": "organization",
+	},
+	"branch": {
+		"repository":    "repository",
+		"last_build":    "build",
+		"recent_builds": "build",
+	},
+	"broadcast": {
+		"recipient": "",
+	},
+	"build": {
+		"repository": "repository",
+		"branch":     "branch",
+		"tag":        "tag",
+		"commit":     "commit",
+		"jobs":       "job",
+		"stages":     "stage",
+		"created_by": "owner",
+	},
+	"commit": {},
+	"cron": {
+		"repository": "repository",
+		"branch":     "branch",
+	},
+	"installation": {
+		"owner": "owner",
+	},
+	"job": {
+		"build":      "build",
+		"stage":      "stage",
+		"repository": "repository",
+		"commit":     "commit",
+		"owner":      "owner",
+		"config":     "",
+	},
+	"organization": {
+		"repositories": "repository",
+		"installation": "installation",
+	},
+	"owner": {
+		"repositories": "repository",
+		"installation": "installation",
+	},
+	"repository": {
+		"owner":          "owner",
+		"default_branch": "branch",
+		"current_build":  "build",
+	},
+	"request": {
+		"repository": "repository",
+		"commit":     "commit",
+		"builds":     "build",
+		"owner":      "owner",
+	},
+	"stage": {
+		"jobs": "job",
+	},
+	"tag": {},
+	"user": {
+		"repositories": "repository",
+		"installation": "installation",
+		"emails":       "",
+	},
+}
+
+// ActiveInclude lists the attributes of active builds which can be eager loaded
+var ActiveInclude = struct {
+	// The active builds
+	Builds string
+}{
+	Builds: "active.builds",
+}
+
+// BetaMigrationRequestInclude lists the attributes of a beta migration request which can be eager loaded
+var BetaMigrationRequestInclude = struct {
+	// The organizations of the beta migration request
+	Organizations string
+}{
+	Organizations: "beta_migration_request.organizations",
+}
+
+// BranchInclude lists the attributes of a branch which can be eager loaded
+var BranchInclude = struct {
+	// GitHub repository the branch belongs to
+	Repository string
+	// Last build on the branch
+	LastBuild string
+	// Last 10 builds on the branch
+	RecentBuilds string
+}{
+	Repository:   "branch.repository",
+	LastBuild:    "branch.last_build",
+	RecentBuilds: "branch.recent_builds",
+}
+
+// BroadcastInclude lists the attributes of a broadcast which can be eager loaded
+var BroadcastInclude = struct {
+	// Either a user, organization or repository
+	Recipient string
+}{
+	Recipient: "broadcast.recipient",
+}
+
+// BuildInclude lists the attributes of a build which can be eager loaded
+var BuildInclude = struct {
+	// GitHub repository the build is associated with
+	Repository string
+	// The branch the build is associated with
+	Branch string
+	// The build's tag
+	Tag string
+	// The commit the build is associated with
+	Commit string
+	// List of jobs that are part of the build's matrix
+	Jobs string
+	// The stages of the build
+	Stages string
+	// The User or Organization that created the build
+	CreatedBy string
+}{
+	Repository: "build.repository",
+	Branch:     "build.branch",
+	Tag:        "build.tag",
+	Commit:     "build.commit",
+	Jobs:       "build.jobs",
+	Stages:     "build.stages",
+	CreatedBy:  "build.created_by",
+}
+
+// CronInclude lists the attributes of a cron which can be eager loaded
+var CronInclude = struct {
+	// Github repository to which the cron belongs
+	Repository string
+	// Git branch of repository to which the cron belongs
+	Branch string
+}{
+	Repository: "cron.repository",
+	Branch:     "cron.branch",
+}
+
+// InstallationInclude lists the attributes of an installation which can be eager loaded
+var InstallationInclude = struct {
+	// GitHub user or organization the installation belongs to
+	Owner string
+}{
+	Owner: "installation.owner",
+}
+
+// JobInclude lists the attributes of a job which can be eager loaded
+var JobInclude = struct {
+	// The build the job is associated with
+	Build string
+	// The stage the job belongs to
+	Stage string
+	// GitHub repository the job is associated with
+	Repository string
+	// The commit the job is associated with
+	Commit string
+	// GitHub user or organization the job belongs to
+	Owner string
+	// The job's config
+	Config string
+}{
+	Build:      "job.build",
+	Stage:      "job.stage",
+	Repository: "job.repository",
+	Commit:     "job.commit",
+	Owner:      "job.owner",
+	Config:     "job.config",
+}
+
+// OrganizationInclude lists the attributes of an organization which can be eager loaded
+var OrganizationInclude = struct {
+	// Repositories belonging to the organization
+	Repositories string
+	// Installation belonging to the organization
+	Installation string
+}{
+	Repositories: "organization.repositories",
+	Installation: "organization.installation",
+}
+
+// OwnerInclude lists the attributes of an owner which can be eager loaded
+var OwnerInclude = struct {
+	// Repositories belonging to the owner
+	Repositories string
+	// Installation belonging to the owner
+	Installation string
+}{
+	Repositories: "owner.repositories",
+	Installation: "owner.installation",
+}
+
+// RepositoryInclude lists the attributes of a repository which can be eager loaded
+var RepositoryInclude = struct {
+	// GitHub user or organization the repository belongs to
+	Owner string
+	// The default branch on GitHub
+	DefaultBranch string
+	// The most recently started build of the repository
+	CurrentBuild string
+}{
+	Owner:         "repository.owner",
+	DefaultBranch: "repository.default_branch",
+	CurrentBuild:  "repository.current_build",
+}
+
+// RequestInclude lists the attributes of a request which can be eager loaded
+var RequestInclude = struct {
+	// GitHub repository the request belongs to
+	Repository string
+	// The commit the request is associated with
+	Commit string
+	// The request's builds
+	Builds string
+	// GitHub user or organization the request belongs to
+	Owner string
+}{
+	Repository: "request.repository",
+	Commit:     "request.commit",
+	Builds:     "request.builds",
+	Owner:      "request.owner",
+}
+
+// StageInclude lists the attributes of a stage which can be eager loaded
+var StageInclude = struct {
+	// The jobs of the stage
+	Jobs string
+}{
+	Jobs: "stage.jobs",
+}
+
+// UserInclude lists the attributes of a user which can be eager loaded
+var UserInclude = struct {
+	// Repositories belonging to the user
+	Repositories string
+	// Installation belonging to the user
+	Installation string
+	// The user's emails
+	Emails string
+}{
+	Repositories: "user.repositories",
+	Installation: "user.installation",
+	Emails:       "user.emails",
+}
+
+// InvalidIncludeError is returned when an attribute which cannot be
+// eager loaded from an endpoint is specified in its Include option
+type InvalidIncludeError struct {
+	// The resource type the endpoint returns
+	Resource string
+	// The offending attribute
+	Include string
+}
+
+func (e *InvalidIncludeError) Error() string {
+	return fmt.Sprintf("%q cannot be eager loaded from %s endpoints", e.Include, e.Resource)
+}
+
+// EagerLoadable returns the attributes which can be eager loaded
+// from endpoints returning the given resource type, e.g. "build".
+// The attributes of the resources related to the given one are included,
+// since they can be eager loaded along with it (e.g. "job.config" for builds).
+func EagerLoadable(resource string) []string {
+	var includes []string
+	for r := range reachableResources(resource) {
+		for attr := range eagerLoadable[r] {
+			includes = append(includes, r+"."+attr)
+		}
+	}
+
+	sort.Strings(includes)
+	return includes
+}
+
+// reachableResources returns the resource types which can be
+// reached from the given one by following eager loadable attributes
+func reachableResources(resource string) map[string]bool {
+	reached := map[string]bool{}
+	queue := []string{resource}
+	for len(queue) > 0 {
+		r := queue[0]
+		queue = queue[1:]
+
+		if reached[r] {
+			continue
+		}
+		if _, ok := eagerLoadable[r]; !ok {
+			continue
+		}
+		reached[r] = true
+
+		for _, next := range eagerLoadable[r] {
+			if next != "" {
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	return reached
+}
+
+// validateIncludes checks that every attribute in include
+// can be eager loaded from endpoints returning the given resource type
+func validateIncludes(resource string, include []string) error {
+	if len(include) == 0 {
+		return nil
+	}
+
+	reachable := reachableResources(resource)
+	for _, inc := range include {
+		i := strings.Index(inc, ".")
+		if i < 0 || !reachable[inc[:i]] {
+			return &InvalidIncludeError{Resource: resource, Include: inc}
+		}
+		if _, ok := eagerLoadable[inc[:i]][inc[i+1:]]; !ok {
+			return &InvalidIncludeError{Resource: resource, Include: inc}
+		}
+	}
+
+	return nil
+}
+
+// includer is implemented by options which specify attributes to eager load
+type includer interface {
+	includes() (resource string, include []string)
+}
+
+func (opt *ActiveOption) includes() (string, []string) {
+	return "active", opt.Include
+}
+
+func (opt *BetaMigrationRequestsOption) includes() (string, []string) {
+	return "beta_migration_request", opt.Include
+}
+
+func (opt *BranchOption) includes() (string, []string) {
+	return "branch", opt.Include
+}
+
+func (opt *BranchesOption) includes() (string, []string) {
+	return "branch", opt.Include
+}
+
+func (opt *BroadcastsOption) includes() (string, []string) {
+	return "broadcast", opt.Include
+}
+
+func (opt *BuildOption) includes() (string, []string) {
+	return "build", opt.Include
+}
+
+func (opt *BuildsOption) includes() (string, []string) {
+	return "build", opt.Include
+}
+
+func (opt *BuildsByRepoOption) includes() (string, []string) {
+	return "build", opt.Include
+}
+
+func (opt *CronOption) includes() (string, []string) {
+	return "cron", opt.Include
+}
+
+func (opt *CronsOption) includes() (string, []string) {
+	return "cron", opt.Include
+}
+
+func (opt *InstallationOption) includes() (string, []string) {
+	return "installation", opt.Include
+}
+
+func (opt *JobOption) includes() (string, []string) {
+	return "job", opt.Include
+}
+
+func (opt *JobsOption) includes() (string, []string) {
+	return "job", opt.Include
+}
+
+func (opt *OrganizationOption) includes() (string, []string) {
+	return "organization", opt.Include
+}
+
+func (opt *OrganizationsOption) includes() (string, []string) {
+	return "organization", opt.Include
+}
+
+func (opt *OwnerOption) includes() (string, []string) {
+	return "owner", opt.Include
+}
+
+func (opt *RepositoryOption) includes() (string, []string) {
+	return "repository", opt.Include
+}
+
+func (opt *RepositoriesOption) includes() (string, []string) {
+	return "repository", opt.Include
+}
+
+func (opt *RequestOption) includes() (string, []string) {
+	return "request", opt.Include
+}
+
+func (opt *RequestsOption) includes() (string, []string) {
+	return "request", opt.Include
+}
+
+func (opt *StagesOption) includes() (string, []string) {
+	return "stage", opt.Include
+}
+
+func (opt *UserOption) includes() (string, []string) {
+	return "user", opt.Include
+}
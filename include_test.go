@@ -0,0 +1,116 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestInclude_constantsAreEagerLoadable(t *testing.T) {
+	cases := []struct {
+		resource string
+		include  interface{}
+	}{
+		{"active", ActiveInclude},
+		{"beta_migration_request", BetaMigrationRequestInclude},
+		{"branch", BranchInclude},
+		{"broadcast", BroadcastInclude},
+		{"build", BuildInclude},
+		{"cron", CronInclude},
+		{"installation", InstallationInclude},
+		{"job", JobInclude},
+		{"organization", OrganizationInclude},
+		{"owner", OwnerInclude},
+		{"repository", RepositoryInclude},
+		{"request", RequestInclude},
+		{"stage", StageInclude},
+		{"user", UserInclude},
+	}
+
+	for _, c := range cases {
+		v := reflect.ValueOf(c.include)
+		for i := 0; i < v.NumField(); i++ {
+			inc := v.Field(i).String()
+			if err := validateIncludes(c.resource, []string{inc}); err != nil {
+				t.Errorf("%s: %s is not eager loadable: %v", c.resource, v.Type().Field(i).Name, err)
+			}
+		}
+	}
+}
+
+func TestValidateIncludes(t *testing.T) {
+	cases := []struct {
+		resource string
+		include  []string
+		valid    bool
+	}{
+		{"build", nil, true},
+		{"build", []string{BuildInclude.Commit, BuildInclude.Jobs}, true},
+		{"build", []string{BuildInclude.Jobs, JobInclude.Config}, true},
+		{"build", []string{RepositoryInclude.CurrentBuild}, true},
+		{"build", []string{"build.commits"}, false},
+		{"build", []string{"commit"}, false},
+		{"stage", []string{BroadcastInclude.Recipient}, false},
+		{"commit", []string{BuildInclude.Commit}, false},
+		{"unknown", []string{BuildInclude.Commit}, false},
+	}
+
+	for i, c := range cases {
+		err := validateIncludes(c.resource, c.include)
+		if got := err == nil; got != c.valid {
+			t.Errorf("#%d validateIncludes(%q, %v) returned %v, want valid: %v", i, c.resource, c.include, err, c.valid)
+		}
+	}
+}
+
+func TestEagerLoadable(t *testing.T) {
+	got := EagerLoadable("stage")
+	want := []string{
+		"build.branch",
+		"build.commit",
+		"build.created_by",
+		"build.jobs",
+		"build.repository",
+		"build.stages",
+		"build.tag",
+	}
+
+	set := map[string]bool{}
+	for _, inc := range got {
+		set[inc] = true
+	}
+	for _, inc := range append(want, StageInclude.Jobs, JobInclude.Config) {
+		if !set[inc] {
+			t.Errorf("EagerLoadable(%q) does not contain %q", "stage", inc)
+		}
+	}
+	if set[BroadcastInclude.Recipient] {
+		t.Errorf("EagerLoadable(%q) contains %q", "stage", BroadcastInclude.Recipient)
+	}
+
+	if got := EagerLoadable("commit"); len(got) != 0 {
+		t.Errorf("EagerLoadable(%q) returned %v, want none", "commit", got)
+	}
+}
+
+func TestInclude_invalidIncludeIsRejected(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/builds", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %s", r.URL)
+	})
+
+	_, _, err := client.Builds.List(context.Background(), &BuildsOption{Include: []string{"build.commits"}})
+
+	want := &InvalidIncludeError{Resource: "build", Include: "build.commits"}
+	if !reflect.DeepEqual(err, want) {
+		t.Errorf("Builds.List returned error %v, want %v", err, want)
+	}
+}
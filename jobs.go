@@ -62,8 +62,8 @@ type JobsOption struct {
 	Limit int `url:"limit,omitempty"`
 	// How many jobs to skip before the first entry in the response
 	Offset int `url:"offset,omitempty"`
-	// Attributes to sort jobs by
-	SortBy []string `url:"sort_by,omitempty,comma"`
+	// Attributes to sort jobs by, e.g. SortOrder{SortDesc(JobSortFieldId)}
+	SortBy SortOrder `url:"sort_by,omitempty"`
 	// // Current state of the job
 	State []string `url:"state,omitempty,comma"`
 	// List of attributes to eager load
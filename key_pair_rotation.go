@@ -0,0 +1,149 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+)
+
+// DefaultKeyPairBits is the size of the keys generated by GenerateKeyPairBody
+// when no size is specified
+const DefaultKeyPairBits = 4096
+
+// FingerprintMismatchError is returned when the fingerprint of a key pair
+// uploaded to Travis CI does not match the fingerprint of the local key
+type FingerprintMismatchError struct {
+	// The fingerprint of the local key
+	Want string
+	// The fingerprint returned by Travis CI
+	Got string
+}
+
+func (e *FingerprintMismatchError) Error() string {
+	return fmt.Sprintf("travis: the key pair fingerprint is %s, want %s", e.Got, e.Want)
+}
+
+// KeyPairRotation is the result of rotating the key pair of a repository
+type KeyPairRotation struct {
+	// The slug of the repository
+	RepoSlug string
+	// The key pair before the rotation, nil if the repository had none.
+	// Its public key should be removed from the deploy keys of the repository on GitHub.
+	Old *KeyPair
+	// The key pair after the rotation.
+	// Its public key should be added to the deploy keys of the repository on GitHub.
+	New *KeyPair
+	// The error which stopped the rotation, if any
+	Err error
+}
+
+// GenerateKeyPairBody generates an RSA private key of the provided size
+// and returns it as a key pair body to be uploaded to Travis CI.
+// bits defaults to DefaultKeyPairBits when 0.
+//
+// Travis CI only accepts RSA keys for key pairs, which GitHub also accepts as
+// deploy keys, so Ed25519 keys are not supported.
+func GenerateKeyPairBody(description string, bits int) (*KeyPairBody, error) {
+	if bits == 0 {
+		bits = DefaultKeyPairBits
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return nil, err
+	}
+
+	value := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	return &KeyPairBody{Description: description, Value: string(value)}, nil
+}
+
+// Fingerprint returns the fingerprint of the public key of the private key
+// in the format of KeyPair.Fingerprint
+func (b *KeyPairBody) Fingerprint() (string, error) {
+	key, err := parsePrivateKey(b.Value)
+	if err != nil {
+		return "", err
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", err
+	}
+
+	return Fingerprint(string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})))
+}
+
+// RotateByRepoSlug replaces the key pair of the repository with the provided slug,
+// or creates it if the repository has none, and verifies that the fingerprint
+// returned by Travis CI matches the one of keyPair
+//
+// Travis CI API docs: https://developer.travis-ci.com/resource/key_pair#update
+func (ks *KeyPairService) RotateByRepoSlug(ctx context.Context, repoSlug string, keyPair *KeyPairBody) (*KeyPairRotation, *http.Response, error) {
+	fingerprint, err := keyPair.Fingerprint()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rotation := &KeyPairRotation{RepoSlug: repoSlug}
+
+	old, resp, err := ks.FindByRepoSlug(ctx, repoSlug)
+	switch {
+	case err == nil:
+		rotation.Old = old
+		rotation.New, resp, err = ks.UpdateByRepoSlug(ctx, repoSlug, keyPair)
+	case resp != nil && resp.StatusCode == http.StatusNotFound:
+		rotation.New, resp, err = ks.CreateByRepoSlug(ctx, repoSlug, keyPair)
+	}
+	if err != nil {
+		return nil, resp, err
+	}
+
+	if rotation.New.Fingerprint == nil || *rotation.New.Fingerprint != fingerprint {
+		got := ""
+		if rotation.New.Fingerprint != nil {
+			got = *rotation.New.Fingerprint
+		}
+		return rotation, resp, &FingerprintMismatchError{Want: fingerprint, Got: got}
+	}
+
+	return rotation, resp, nil
+}
+
+// RotateByRepoSlugs generates a new key pair for each of the repositories with
+// the provided slugs and rotates it, see RotateByRepoSlug.
+// Each repository gets its own key, as GitHub does not allow a deploy key to be
+// used by more than one repository.
+//
+// A failure does not stop the rotation of the other repositories,
+// so the Err field of each rotation has to be checked.
+func (ks *KeyPairService) RotateByRepoSlugs(ctx context.Context, repoSlugs []string, description string, bits int) []*KeyPairRotation {
+	rotations := make([]*KeyPairRotation, 0, len(repoSlugs))
+
+	for _, slug := range repoSlugs {
+		body, err := GenerateKeyPairBody(description, bits)
+		if err != nil {
+			rotations = append(rotations, &KeyPairRotation{RepoSlug: slug, Err: err})
+			continue
+		}
+
+		rotation, _, err := ks.RotateByRepoSlug(ctx, slug, body)
+		if rotation == nil {
+			rotation = &KeyPairRotation{RepoSlug: slug}
+		}
+		rotation.Err = err
+
+		rotations = append(rotations, rotation)
+	}
+
+	return rotations
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// testKeyPairHandler serves a key pair endpoint storing the uploaded key,
+// and answering with its actual fingerprint unless fingerprint is set
+func testKeyPairHandler(t *testing.T, existing *KeyPair, fingerprint string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			if existing == nil {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"error_type":"not_found","error_message":"key_pair not found"}`)
+				return
+			}
+			json.NewEncoder(w).Encode(existing)
+		case http.MethodPost, http.MethodPatch:
+			if (existing == nil) != (r.Method == http.MethodPost) {
+				t.Errorf("Request method: %v", r.Method)
+			}
+
+			var body KeyPairBody
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+
+			f := fingerprint
+			if f == "" {
+				var err error
+				if f, err = body.Fingerprint(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			json.NewEncoder(w).Encode(&KeyPair{Description: String(body.Description), Fingerprint: String(f)})
+		}
+	}
+}
+
+func TestGenerateKeyPairBody(t *testing.T) {
+	body, err := GenerateKeyPairBody("deploy key", 1024)
+	if err != nil {
+		t.Fatalf("GenerateKeyPairBody returned error: %v", err)
+	}
+
+	key, err := parsePrivateKey(body.Value)
+	if err != nil {
+		t.Fatalf("GenerateKeyPairBody generated an invalid key: %v", err)
+	}
+	if key.N.BitLen() != 1024 || body.Description != "deploy key" {
+		t.Errorf("GenerateKeyPairBody returned a %d bits key described as %q", key.N.BitLen(), body.Description)
+	}
+}
+
+func TestKeyPairService_RotateByRepoSlug(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	old := &KeyPair{Description: String("old"), PublicKey: String(testKeyPairPublicKey), Fingerprint: String(testKeyPairFingerprint)}
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/key_pair", testRepoSlug), testKeyPairHandler(t, old, ""))
+
+	body, err := GenerateKeyPairBody("new", 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fingerprint, _ := body.Fingerprint()
+
+	rotation, _, err := client.KeyPair.RotateByRepoSlug(context.Background(), testRepoSlug, body)
+
+	if err != nil {
+		t.Fatalf("KeyPair.RotateByRepoSlug returned error: %v", err)
+	}
+
+	want := &KeyPairRotation{
+		RepoSlug: testRepoSlug,
+		Old:      old,
+		New:      &KeyPair{Description: String("new"), Fingerprint: String(fingerprint)},
+	}
+	if !reflect.DeepEqual(rotation, want) {
+		t.Errorf("KeyPair.RotateByRepoSlug returned %+v, want %+v", rotation, want)
+	}
+}
+
+func TestKeyPairService_RotateByRepoSlug_withoutKeyPair(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/key_pair", testRepoSlug), testKeyPairHandler(t, nil, ""))
+
+	body, err := GenerateKeyPairBody("new", 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotation, _, err := client.KeyPair.RotateByRepoSlug(context.Background(), testRepoSlug, body)
+
+	if err != nil {
+		t.Fatalf("KeyPair.RotateByRepoSlug returned error: %v", err)
+	}
+	if rotation.Old != nil || rotation.New == nil {
+		t.Errorf("KeyPair.RotateByRepoSlug returned %+v", rotation)
+	}
+}
+
+func TestKeyPairService_RotateByRepoSlug_fingerprintMismatch(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/key_pair", testRepoSlug), testKeyPairHandler(t, nil, testKeyPairFingerprint))
+
+	body, err := GenerateKeyPairBody("new", 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fingerprint, _ := body.Fingerprint()
+
+	_, _, err = client.KeyPair.RotateByRepoSlug(context.Background(), testRepoSlug, body)
+
+	want := &FingerprintMismatchError{Want: fingerprint, Got: testKeyPairFingerprint}
+	if !reflect.DeepEqual(err, want) {
+		t.Errorf("KeyPair.RotateByRepoSlug returned error %v, want %v", err, want)
+	}
+}
+
+func TestKeyPairService_RotateByRepoSlugs(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/key_pair", testRepoSlug), testKeyPairHandler(t, nil, ""))
+	mux.HandleFunc("/repo/shuheiktgw/forbidden/key_pair", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"error_type":"insufficient_access","error_message":"forbidden"}`)
+	})
+
+	rotations := client.KeyPair.RotateByRepoSlugs(context.Background(), []string{testRepoSlug, "shuheiktgw/forbidden"}, "deploy key", 1024)
+
+	if len(rotations) != 2 {
+		t.Fatalf("KeyPair.RotateByRepoSlugs returned %d rotations, want 2", len(rotations))
+	}
+	if rotations[0].Err != nil || rotations[0].New == nil {
+		t.Errorf("KeyPair.RotateByRepoSlugs returned %+v for %s", rotations[0], testRepoSlug)
+	}
+	if rotations[1].Err == nil || rotations[1].RepoSlug != "shuheiktgw/forbidden" {
+		t.Errorf("KeyPair.RotateByRepoSlugs returned %+v for shuheiktgw/forbidden", rotations[1])
+	}
+}
@@ -8,6 +8,7 @@ package travis
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 )
 
@@ -80,3 +81,21 @@ func (ls *LogsService) DeleteByJobId(ctx context.Context, jobId uint) (*Log, *ht
 
 	return &log, resp, err
 }
+
+// DownloadByJobId writes the raw text of a job's log to w.
+// Wrap w with NewRedactor to mask secrets in the log.
+//
+// Travis CI API docs: https://developer.travis-ci.com/resource/log#find
+func (ls *LogsService) DownloadByJobId(ctx context.Context, jobId uint, w io.Writer) (*http.Response, error) {
+	u, err := urlWithOptions(fmt.Sprintf("job/%d/log.txt", jobId), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := ls.client.NewRequest(http.MethodGet, u, nil, map[string]string{"Accept": "text/plain"})
+	if err != nil {
+		return nil, err
+	}
+
+	return ls.client.Do(ctx, req, w)
+}
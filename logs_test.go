@@ -6,6 +6,7 @@
 package travis
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"net/http"
@@ -54,3 +55,33 @@ func TestLogsService_DeleteByJobId(t *testing.T) {
 		t.Errorf("Log.DeleteByJobId returned %+v, want %+v", log, want)
 	}
 }
+
+func TestLogsService_DownloadByJobId(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc(fmt.Sprintf("/job/%d/log.txt", testJobId), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		if got := r.Header.Get("Accept"); got != "text/plain" {
+			t.Errorf("Request Accept header: %v, want text/plain", got)
+		}
+		fmt.Fprint(w, "$ echo $TOKEN\nsecret-token\n")
+	})
+
+	var b bytes.Buffer
+	r := NewRedactor(&b, "secret-token")
+	_, err := client.Logs.DownloadByJobId(context.Background(), testJobId, r)
+
+	if err != nil {
+		t.Errorf("Log.DownloadByJobId returned error: %v", err)
+	}
+	r.Close()
+
+	if want := "$ echo $TOKEN\n[secure]\n"; b.String() != want {
+		t.Errorf("Log.DownloadByJobId wrote %q, want %q", b.String(), want)
+	}
+
+	if _, ok := client.Headers["Accept"]; ok {
+		t.Error("Log.DownloadByJobId changed the client headers")
+	}
+}
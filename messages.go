@@ -31,7 +31,7 @@ type Message struct {
 	Key *string `json:"key"`
 	// The message's code
 	Code *string `json:"code"`
-	// The message's args
+	// The message's args, see DecodeArgs
 	Args json.RawMessage `json:"args"`
 	*Metadata
 }
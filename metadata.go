@@ -23,11 +23,13 @@ type Metadata struct {
 }
 
 // IsStandard tells if the struct is in a standard representation
+// It returns false if the representation is unknown
 func (m *Metadata) IsStandard() bool {
-	return *m.Representation == standardRepresentation
+	return m != nil && m.Representation != nil && *m.Representation == standardRepresentation
 }
 
-// IsStandard tells if the struct is in a minimal representation
+// IsMinimal tells if the struct is in a minimal representation
+// It returns false if the representation is unknown
 func (m *Metadata) IsMinimal() bool {
-	return *m.Representation == minimalRepresentation
+	return m != nil && m.Representation != nil && *m.Representation == minimalRepresentation
 }
@@ -44,3 +44,20 @@ func TestMetadata_isMinimal(t *testing.T) {
 		}
 	}
 }
+
+func TestMetadata_unknownRepresentation(t *testing.T) {
+	cases := []*Metadata{
+		nil,
+		{},
+		{Href: String("/build/1")},
+	}
+
+	for i, m := range cases {
+		if m.IsStandard() {
+			t.Fatalf("#%d invalid: IsStandard returned true", i)
+		}
+		if m.IsMinimal() {
+			t.Fatalf("#%d invalid: IsMinimal returned true", i)
+		}
+	}
+}
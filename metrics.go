@@ -0,0 +1,365 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MetricsOption specifies the options of a MetricsExporter
+type MetricsOption struct {
+	// Owners whose active builds are counted
+	Owners []string
+	// Repositories whose builds, jobs and crons are measured
+	RepoSlugs []string
+	// How often the metrics are collected by Run. Defaults to a minute.
+	Interval time.Duration
+	// How many recent builds of each repository are measured. Defaults to 100.
+	BuildsLimit int
+	// Upper bounds of the build duration histogram buckets, in seconds
+	DurationBuckets []float64
+	// Upper bounds of the job queue wait histogram buckets, in seconds
+	QueueWaitBuckets []float64
+}
+
+var (
+	defaultDurationBuckets  = []float64{60, 120, 300, 600, 900, 1200, 1800, 2700, 3600}
+	defaultQueueWaitBuckets = []float64{5, 10, 30, 60, 120, 300, 600, 1800}
+)
+
+// MetricsExporter is an http.Handler exposing metrics about builds, jobs
+// and crons in the Prometheus text exposition format:
+//
+//	travis_builds{repo,branch,state}                 recent builds by state
+//	travis_build_duration_seconds{repo}              histogram of the duration of recent builds
+//	travis_job_queue_wait_seconds{repo}              histogram of the time jobs of recent builds waited to start
+//	travis_active_builds{owner}                      builds currently running or queued
+//	travis_cron_last_run_age_seconds{repo,branch}    time since crons last ran
+//	travis_exporter_last_success_timestamp_seconds   when the metrics were last collected
+//	travis_exporter_up                               whether the last collection succeeded
+//
+// The metrics are collected by Collect, or periodically by Run,
+// so scraping the handler does not hit the API.
+type MetricsExporter struct {
+	client *Client
+	opt    MetricsOption
+
+	mu          sync.RWMutex
+	families    []*metricFamily
+	up          bool
+	lastSuccess time.Time
+
+	// now returns the current time, replaced in tests
+	now func() time.Time
+}
+
+// NewMetricsExporter returns a MetricsExporter collecting metrics with client
+func NewMetricsExporter(client *Client, opt *MetricsOption) *MetricsExporter {
+	e := &MetricsExporter{client: client, now: time.Now}
+	if opt != nil {
+		e.opt = *opt
+	}
+	if e.opt.Interval == 0 {
+		e.opt.Interval = time.Minute
+	}
+	if e.opt.BuildsLimit == 0 {
+		e.opt.BuildsLimit = 100
+	}
+	if e.opt.DurationBuckets == nil {
+		e.opt.DurationBuckets = defaultDurationBuckets
+	}
+	if e.opt.QueueWaitBuckets == nil {
+		e.opt.QueueWaitBuckets = defaultQueueWaitBuckets
+	}
+	return e
+}
+
+// Run collects the metrics immediately, then every Interval until ctx is done.
+// Failed collections keep the previous metrics and set travis_exporter_up to 0.
+func (e *MetricsExporter) Run(ctx context.Context) error {
+	ticker := time.NewTicker(e.opt.Interval)
+	defer ticker.Stop()
+
+	for {
+		e.Collect(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Collect collects the metrics once
+func (e *MetricsExporter) Collect(ctx context.Context) (*http.Response, error) {
+	families, resp, err := e.collect(ctx)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.up = err == nil
+	if err == nil {
+		e.families = families
+		e.lastSuccess = e.now()
+	}
+
+	return resp, err
+}
+
+func (e *MetricsExporter) collect(ctx context.Context) ([]*metricFamily, *http.Response, error) {
+	now := e.now()
+
+	builds := newMetricFamily("travis_builds", "Number of recent builds by state.", "gauge")
+	durations := newHistogram("travis_build_duration_seconds", "Duration of recent builds.", e.opt.DurationBuckets)
+	queueWaits := newHistogram("travis_job_queue_wait_seconds", "Time jobs of recent builds waited before starting.", e.opt.QueueWaitBuckets)
+	active := newMetricFamily("travis_active_builds", "Number of builds currently running or queued.", "gauge")
+	cronAges := newMetricFamily("travis_cron_last_run_age_seconds", "Time since crons last ran.", "gauge")
+
+	var resp *http.Response
+	var err error
+
+	for _, owner := range e.opt.Owners {
+		var ownerBuilds []*Build
+		ownerBuilds, resp, err = e.client.Active.FindByOwner(ctx, owner, nil)
+		if err != nil {
+			return nil, resp, err
+		}
+		active.add(float64(len(ownerBuilds)), "owner", owner)
+	}
+
+	for _, slug := range e.opt.RepoSlugs {
+		opt := &BuildsByRepoOption{
+			Limit:   e.opt.BuildsLimit,
+			Include: []string{BuildInclude.Jobs},
+		}
+		var repoBuilds []*Build
+		repoBuilds, resp, err = e.client.Builds.ListByRepoSlug(ctx, slug, opt)
+		if err != nil {
+			return nil, resp, err
+		}
+
+		states := map[[2]string]int{}
+		for _, b := range repoBuilds {
+			branch := ""
+			if b.Branch != nil && b.Branch.Name != nil {
+				branch = *b.Branch.Name
+			}
+			if b.State != nil {
+				states[[2]string{branch, *b.State}]++
+			}
+
+			if b.Duration != nil && !buildRunning(b) {
+				durations.observe(float64(*b.Duration), "repo", slug)
+			}
+
+			for _, j := range b.Jobs {
+				created, ok := parseTime(j.CreatedAt)
+				if !ok {
+					continue
+				}
+				started, ok := parseTime(j.StartedAt)
+				if !ok {
+					continue
+				}
+				queueWaits.observe(math.Max(0, started.Sub(created).Seconds()), "repo", slug)
+			}
+		}
+		for k, n := range states {
+			builds.add(float64(n), "repo", slug, "branch", k[0], "state", k[1])
+		}
+
+		var crons []*Cron
+		crons, resp, err = e.client.Crons.listAllByRepoSlug(ctx, slug)
+		if err != nil {
+			return nil, resp, err
+		}
+		for _, c := range crons {
+			lastRun, ok := parseTime(c.LastRun)
+			if !ok {
+				continue
+			}
+			branch := ""
+			if c.Branch != nil && c.Branch.Name != nil {
+				branch = *c.Branch.Name
+			}
+			cronAges.add(now.Sub(lastRun).Seconds(), "repo", slug, "branch", branch)
+		}
+	}
+
+	return []*metricFamily{builds, durations.family(), queueWaits.family(), active, cronAges}, resp, nil
+}
+
+// ServeHTTP serves the last collected metrics
+func (e *MetricsExporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(e.render())
+}
+
+func (e *MetricsExporter) render() []byte {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var b bytes.Buffer
+	for _, f := range e.families {
+		f.write(&b)
+	}
+
+	lastSuccess := newMetricFamily("travis_exporter_last_success_timestamp_seconds", "When the metrics were last collected successfully.", "gauge")
+	if !e.lastSuccess.IsZero() {
+		lastSuccess.add(float64(e.lastSuccess.Unix()))
+	}
+	lastSuccess.write(&b)
+
+	up := newMetricFamily("travis_exporter_up", "Whether the last collection of the metrics succeeded.", "gauge")
+	if e.up {
+		up.add(1)
+	} else {
+		up.add(0)
+	}
+	up.write(&b)
+
+	return b.Bytes()
+}
+
+// metricFamily is a metric in the Prometheus text exposition format
+type metricFamily struct {
+	name, help, typ string
+	samples         []*metricSample
+}
+
+type metricSample struct {
+	// suffix is appended to the name of the family, e.g. _bucket
+	suffix string
+	// labels are name and value pairs
+	labels []string
+	value  float64
+}
+
+func newMetricFamily(name, help, typ string) *metricFamily {
+	return &metricFamily{name: name, help: help, typ: typ}
+}
+
+// add adds a sample with the provided label name and value pairs
+func (f *metricFamily) add(value float64, labels ...string) {
+	f.samples = append(f.samples, &metricSample{labels: labels, value: value})
+}
+
+var metricLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func (f *metricFamily) write(b *bytes.Buffer) {
+	fmt.Fprintf(b, "# HELP %s %s\n", f.name, f.help)
+	fmt.Fprintf(b, "# TYPE %s %s\n", f.name, f.typ)
+
+	lines := make([]string, 0, len(f.samples))
+	for _, s := range f.samples {
+		var line strings.Builder
+		line.WriteString(f.name + s.suffix)
+		if len(s.labels) > 0 {
+			line.WriteString("{")
+			for i := 0; i+1 < len(s.labels); i += 2 {
+				if i > 0 {
+					line.WriteString(",")
+				}
+				fmt.Fprintf(&line, `%s="%s"`, s.labels[i], metricLabelEscaper.Replace(s.labels[i+1]))
+			}
+			line.WriteString("}")
+		}
+		line.WriteString(" " + formatMetricValue(s.value))
+		lines = append(lines, line.String())
+	}
+
+	// Histograms keep their samples in order, other metrics are sorted
+	// so the output does not depend on map iteration
+	if f.typ != "histogram" {
+		sort.Strings(lines)
+	}
+
+	for _, line := range lines {
+		b.WriteString(line + "\n")
+	}
+}
+
+func formatMetricValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+// histogram accumulates observations into Prometheus histogram buckets
+type histogram struct {
+	name, help string
+	buckets    []float64
+	series     map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	labels []string
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func newHistogram(name, help string, buckets []float64) *histogram {
+	return &histogram{name: name, help: help, buckets: buckets, series: map[string]*histogramSeries{}}
+}
+
+// observe adds v to the series with the provided label name and value pairs
+func (h *histogram) observe(v float64, labels ...string) {
+	key := strings.Join(labels, "\x00")
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{labels: labels, counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+
+	for i, upper := range h.buckets {
+		if v <= upper {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.sum += v
+}
+
+// family returns the cumulative buckets, sum and count of each series
+func (h *histogram) family() *metricFamily {
+	f := newMetricFamily(h.name, h.help, "histogram")
+
+	keys := make([]string, 0, len(h.series))
+	for k := range h.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		s := h.series[k]
+		for i, upper := range h.buckets {
+			f.samples = append(f.samples, &metricSample{suffix: "_bucket", labels: append(append([]string{}, s.labels...), "le", formatMetricValue(upper)), value: float64(s.counts[i])})
+		}
+		f.samples = append(f.samples,
+			&metricSample{suffix: "_bucket", labels: append(append([]string{}, s.labels...), "le", "+Inf"), value: float64(s.count)},
+			&metricSample{suffix: "_sum", labels: s.labels, value: s.sum},
+			&metricSample{suffix: "_count", labels: s.labels, value: float64(s.count)},
+		)
+	}
+
+	return f
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsExporter_Collect(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc(fmt.Sprintf("/owner/%s/active", testOwner), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, `{"builds":[{"id":1},{"id":2}]}`)
+	})
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/builds", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		testFormValues(t, r, values{"limit": "10", "include": "build.jobs"})
+		fmt.Fprint(w, `{"builds":[
+		  {"state":"passed","duration":90,"branch":{"name":"master"},"jobs":[
+		    {"created_at":"2020-01-01T00:00:00Z","started_at":"2020-01-01T00:00:20Z"},
+		    {"created_at":"2020-01-01T00:00:00Z","started_at":"2020-01-01T00:02:00Z"}
+		  ]},
+		  {"state":"failed","duration":400,"branch":{"name":"master"}},
+		  {"state":"started","duration":10,"branch":{"name":"a \"quoted\" branch"},"jobs":[{"created_at":"2020-01-01T00:00:00Z"}]}
+		]}`)
+	})
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/crons", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, `{"crons":[{"branch":{"name":"master"},"last_run":"2020-01-01T00:00:00Z"},{"branch":{"name":"never"}}]}`)
+	})
+
+	e := NewMetricsExporter(client, &MetricsOption{
+		Owners:           []string{testOwner},
+		RepoSlugs:        []string{testRepoSlug},
+		BuildsLimit:      10,
+		DurationBuckets:  []float64{60, 300},
+		QueueWaitBuckets: []float64{30},
+	})
+	e.now = func() time.Time { return time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC) }
+
+	if _, err := e.Collect(context.Background()); err != nil {
+		t.Fatalf("MetricsExporter.Collect returned error: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	want := `# HELP travis_builds Number of recent builds by state.
+# TYPE travis_builds gauge
+travis_builds{repo="shuheiktgw/go-travis-test",branch="a \"quoted\" branch",state="started"} 1
+travis_builds{repo="shuheiktgw/go-travis-test",branch="master",state="failed"} 1
+travis_builds{repo="shuheiktgw/go-travis-test",branch="master",state="passed"} 1
+# HELP travis_build_duration_seconds Duration of recent builds.
+# TYPE travis_build_duration_seconds histogram
+travis_build_duration_seconds_bucket{repo="shuheiktgw/go-travis-test",le="60"} 0
+travis_build_duration_seconds_bucket{repo="shuheiktgw/go-travis-test",le="300"} 1
+travis_build_duration_seconds_bucket{repo="shuheiktgw/go-travis-test",le="+Inf"} 2
+travis_build_duration_seconds_sum{repo="shuheiktgw/go-travis-test"} 490
+travis_build_duration_seconds_count{repo="shuheiktgw/go-travis-test"} 2
+# HELP travis_job_queue_wait_seconds Time jobs of recent builds waited before starting.
+# TYPE travis_job_queue_wait_seconds histogram
+travis_job_queue_wait_seconds_bucket{repo="shuheiktgw/go-travis-test",le="30"} 1
+travis_job_queue_wait_seconds_bucket{repo="shuheiktgw/go-travis-test",le="+Inf"} 2
+travis_job_queue_wait_seconds_sum{repo="shuheiktgw/go-travis-test"} 140
+travis_job_queue_wait_seconds_count{repo="shuheiktgw/go-travis-test"} 2
+# HELP travis_active_builds Number of builds currently running or queued.
+# TYPE travis_active_builds gauge
+travis_active_builds{owner="shuheiktgw"} 2
+# HELP travis_cron_last_run_age_seconds Time since crons last ran.
+# TYPE travis_cron_last_run_age_seconds gauge
+travis_cron_last_run_age_seconds{repo="shuheiktgw/go-travis-test",branch="master"} 3600
+# HELP travis_exporter_last_success_timestamp_seconds When the metrics were last collected successfully.
+# TYPE travis_exporter_last_success_timestamp_seconds gauge
+travis_exporter_last_success_timestamp_seconds 1.5778404e+09
+# HELP travis_exporter_up Whether the last collection of the metrics succeeded.
+# TYPE travis_exporter_up gauge
+travis_exporter_up 1
+`
+	if got := w.Body.String(); got != want {
+		t.Errorf("MetricsExporter.ServeHTTP returned\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMetricsExporter_Collect_error(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc(fmt.Sprintf("/owner/%s/active", testOwner), func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	e := NewMetricsExporter(client, &MetricsOption{Owners: []string{testOwner}})
+
+	if _, err := e.Collect(context.Background()); err == nil {
+		t.Fatal("MetricsExporter.Collect returned no error")
+	}
+
+	if got := string(e.render()); !strings.HasSuffix(got, "travis_exporter_up 0\n") {
+		t.Errorf("MetricsExporter.ServeHTTP returned\n%s\nwant travis_exporter_up 0", got)
+	}
+}
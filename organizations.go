@@ -52,8 +52,8 @@ type OrganizationsOption struct {
 	Limit int `url:"limit,omitempty"`
 	// How many organizations to skip before the first entry in the response
 	Offset int `url:"offset,omitempty"`
-	// Attributes to sort organizations by
-	SortBy string `url:"sort_by,omitempty"`
+	// Attributes to sort organizations by, e.g. SortOrder{SortAsc(OrganizationSortFieldLogin)}
+	SortBy SortOrder `url:"sort_by,omitempty"`
 	// List of attributes to eager load
 	Include []string `url:"include,omitempty,comma"`
 }
@@ -37,7 +37,7 @@ func TestOrganizationsService_Integration_Find(t *testing.T) {
 }
 
 func TestOrganizationsService_Integration_List(t *testing.T) {
-	opt := OrganizationsOption{Limit: 50, Offset: 50, SortBy: "id", Include: []string{"organization.repositories"}}
+	opt := OrganizationsOption{Limit: 50, Offset: 50, SortBy: SortOrder{SortAsc(OrganizationSortFieldId)}, Include: []string{"organization.repositories"}}
 	_, res, err := integrationClient.Organizations.List(context.TODO(), &opt)
 
 	if err != nil {
@@ -46,7 +46,7 @@ func TestOrganizationsService_List(t *testing.T) {
 		fmt.Fprint(w, `{"organizations":[{"id":111,"login":"TestOrg","name":"TestOrg","github_id":12345,"avatar_url":"https:///test.com","education":false}]}`)
 	})
 
-	opt := OrganizationsOption{Limit: 50, Offset: 50, SortBy: "id", Include: []string{"organization.repositories"}}
+	opt := OrganizationsOption{Limit: 50, Offset: 50, SortBy: SortOrder{SortAsc(OrganizationSortFieldId)}, Include: []string{"organization.repositories"}}
 	orgs, _, err := client.Organizations.List(context.Background(), &opt)
 
 	if err != nil {
@@ -0,0 +1,420 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// PerformanceOption specifies the optional parameters of AnalyzePerformance
+type PerformanceOption struct {
+	// Only analyze the builds of this branch
+	Branch string
+	// The window analyzed. Until defaults to now, and Since to 30 days before Until.
+	Since time.Time
+	Until time.Time
+	// The baseline period the window is compared to.
+	// Defaults to the period of the same length preceding the window.
+	BaselineSince time.Time
+	BaselineUntil time.Time
+	// The relative increase of a duration reported as a regression. Defaults to 0.2, i.e. 20%.
+	RegressionThreshold float64
+}
+
+// PerformanceStats are the statistics of the builds of a period
+type PerformanceStats struct {
+	// How many builds started in the period
+	Builds int `json:"builds"`
+	// How many of them passed, and failed or errored
+	Passed int `json:"passed"`
+	Failed int `json:"failed"`
+	// Passed / (Passed + Failed), or 0 if no build finished
+	SuccessRate float64 `json:"success_rate"`
+	// The median and 90th percentile of the duration of the finished builds
+	// The durations are encoded to JSON in seconds.
+	MedianDuration time.Duration `json:"median_duration_seconds"`
+	P90Duration    time.Duration `json:"p90_duration_seconds"`
+	// The median time jobs waited between their creation and their start
+	MedianQueueTime time.Duration `json:"median_queue_time_seconds"`
+}
+
+// StagePerformance is the duration of a build stage over the window and the baseline.
+// The durations are encoded to JSON in seconds.
+type StagePerformance struct {
+	Name string `json:"name"`
+	// How many times the stage ran in the window
+	Runs                   int           `json:"runs"`
+	MedianDuration         time.Duration `json:"median_duration_seconds"`
+	BaselineMedianDuration time.Duration `json:"baseline_median_duration_seconds"`
+}
+
+// PerformanceRegression is a duration which increased over the window compared to the baseline.
+// The durations are encoded to JSON in seconds.
+type PerformanceRegression struct {
+	// The metric, e.g. median_duration or stage:test
+	Metric   string        `json:"metric"`
+	Baseline time.Duration `json:"baseline_seconds"`
+	Current  time.Duration `json:"current_seconds"`
+	// The relative increase, e.g. 0.5 when 50% slower
+	Change float64 `json:"change"`
+}
+
+// PerformanceReport is the result of AnalyzePerformance.
+// It is encoded to JSON with encoding/json, or to CSV with WriteCSV,
+// with durations in seconds in both cases.
+type PerformanceReport struct {
+	RepoSlug      string    `json:"repo_slug"`
+	Branch        string    `json:"branch,omitempty"`
+	Since         time.Time `json:"since"`
+	Until         time.Time `json:"until"`
+	BaselineSince time.Time `json:"baseline_since"`
+	BaselineUntil time.Time `json:"baseline_until"`
+	// The statistics of the window and of the baseline
+	Current  *PerformanceStats `json:"current"`
+	Baseline *PerformanceStats `json:"baseline"`
+	// The stages, slowest first
+	Stages []*StagePerformance `json:"stages"`
+	// The regressions, largest first
+	Regressions []*PerformanceRegression `json:"regressions"`
+}
+
+// jsonSeconds is a duration encoded to JSON as a number of seconds
+type jsonSeconds time.Duration
+
+func (d jsonSeconds) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).Seconds())
+}
+
+func (d *jsonSeconds) UnmarshalJSON(data []byte) error {
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err != nil {
+		return err
+	}
+	*d = jsonSeconds(seconds * float64(time.Second))
+	return nil
+}
+
+// MarshalJSON encodes the statistics with their durations in seconds
+func (s PerformanceStats) MarshalJSON() ([]byte, error) {
+	type stats PerformanceStats
+	return json.Marshal(struct {
+		stats
+		MedianDuration  jsonSeconds `json:"median_duration_seconds"`
+		P90Duration     jsonSeconds `json:"p90_duration_seconds"`
+		MedianQueueTime jsonSeconds `json:"median_queue_time_seconds"`
+	}{stats(s), jsonSeconds(s.MedianDuration), jsonSeconds(s.P90Duration), jsonSeconds(s.MedianQueueTime)})
+}
+
+// UnmarshalJSON decodes the statistics with their durations in seconds
+func (s *PerformanceStats) UnmarshalJSON(data []byte) error {
+	type stats PerformanceStats
+	v := struct {
+		*stats
+		MedianDuration  jsonSeconds `json:"median_duration_seconds"`
+		P90Duration     jsonSeconds `json:"p90_duration_seconds"`
+		MedianQueueTime jsonSeconds `json:"median_queue_time_seconds"`
+	}{stats: (*stats)(s)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	s.MedianDuration, s.P90Duration, s.MedianQueueTime = time.Duration(v.MedianDuration), time.Duration(v.P90Duration), time.Duration(v.MedianQueueTime)
+	return nil
+}
+
+// MarshalJSON encodes the stage with its durations in seconds
+func (s StagePerformance) MarshalJSON() ([]byte, error) {
+	type stage StagePerformance
+	return json.Marshal(struct {
+		stage
+		MedianDuration         jsonSeconds `json:"median_duration_seconds"`
+		BaselineMedianDuration jsonSeconds `json:"baseline_median_duration_seconds"`
+	}{stage(s), jsonSeconds(s.MedianDuration), jsonSeconds(s.BaselineMedianDuration)})
+}
+
+// UnmarshalJSON decodes the stage with its durations in seconds
+func (s *StagePerformance) UnmarshalJSON(data []byte) error {
+	type stage StagePerformance
+	v := struct {
+		*stage
+		MedianDuration         jsonSeconds `json:"median_duration_seconds"`
+		BaselineMedianDuration jsonSeconds `json:"baseline_median_duration_seconds"`
+	}{stage: (*stage)(s)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	s.MedianDuration, s.BaselineMedianDuration = time.Duration(v.MedianDuration), time.Duration(v.BaselineMedianDuration)
+	return nil
+}
+
+// MarshalJSON encodes the regression with its durations in seconds
+func (r PerformanceRegression) MarshalJSON() ([]byte, error) {
+	type regression PerformanceRegression
+	return json.Marshal(struct {
+		regression
+		Baseline jsonSeconds `json:"baseline_seconds"`
+		Current  jsonSeconds `json:"current_seconds"`
+	}{regression(r), jsonSeconds(r.Baseline), jsonSeconds(r.Current)})
+}
+
+// UnmarshalJSON decodes the regression with its durations in seconds
+func (r *PerformanceRegression) UnmarshalJSON(data []byte) error {
+	type regression PerformanceRegression
+	v := struct {
+		*regression
+		Baseline jsonSeconds `json:"baseline_seconds"`
+		Current  jsonSeconds `json:"current_seconds"`
+	}{regression: (*regression)(r)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	r.Baseline, r.Current = time.Duration(v.Baseline), time.Duration(v.Current)
+	return nil
+}
+
+// performanceSamples accumulates the durations of a period
+type performanceSamples struct {
+	stats      PerformanceStats
+	durations  []time.Duration
+	queueTimes []time.Duration
+	stages     map[string][]time.Duration
+}
+
+// AnalyzePerformance computes the success rate, the build durations, the queue
+// times and the stage durations of the builds of a repository over a window,
+// and reports the durations which regressed compared to a baseline period
+func (c *Client) AnalyzePerformance(ctx context.Context, repoSlug string, opt *PerformanceOption) (*PerformanceReport, *http.Response, error) {
+	var o PerformanceOption
+	if opt != nil {
+		o = *opt
+	}
+	if o.Until.IsZero() {
+		o.Until = time.Now()
+	}
+	if o.Since.IsZero() {
+		o.Since = o.Until.AddDate(0, 0, -30)
+	}
+	if o.BaselineUntil.IsZero() {
+		o.BaselineUntil = o.Since
+	}
+	if o.BaselineSince.IsZero() {
+		o.BaselineSince = o.BaselineUntil.Add(-o.Until.Sub(o.Since))
+	}
+	if o.RegressionThreshold == 0 {
+		o.RegressionThreshold = 0.2
+	}
+
+	earliest := o.Since
+	if o.BaselineSince.Before(earliest) {
+		earliest = o.BaselineSince
+	}
+
+	current := &performanceSamples{stages: map[string][]time.Duration{}}
+	baseline := &performanceSamples{stages: map[string][]time.Duration{}}
+
+	listOpt := BuildsByRepoOption{
+		Include: []string{BuildInclude.Jobs},
+		SortBy:  SortOrder{SortDesc(BuildSortFieldId)},
+	}
+	if o.Branch != "" {
+		listOpt.BranchName = []string{o.Branch}
+	}
+
+	var builds []*Build
+	var periods []*performanceSamples
+
+	resp, err := c.Builds.walkByRepoSlug(ctx, repoSlug, listOpt, func(b *Build) bool {
+		started, ok := parseTime(b.StartedAt)
+		if !ok {
+			// Builds which have not started yet, or were canceled before
+			return true
+		}
+		// Builds are listed most recently created first, so the walk stops at the first
+		// build started before the periods. A build restarted long after it was
+		// created may still start within them, and is missed.
+		if started.Before(earliest) {
+			return false
+		}
+
+		var p *performanceSamples
+		switch {
+		case !started.Before(o.Since) && started.Before(o.Until):
+			p = current
+		case !started.Before(o.BaselineSince) && started.Before(o.BaselineUntil):
+			p = baseline
+		default:
+			return true
+		}
+
+		p.add(b)
+		builds = append(builds, b)
+		periods = append(periods, p)
+		return true
+	})
+	if err != nil {
+		return nil, resp, err
+	}
+
+	for i, b := range builds {
+		if b.Id == nil || b.State == nil || !buildStateFinished(*b.State) {
+			continue
+		}
+
+		var stages []*Stage
+		stages, resp, err = c.Stages.ListByBuild(ctx, *b.Id, nil)
+		if err != nil {
+			return nil, resp, err
+		}
+		for _, s := range stages {
+			started, ok1 := parseTime(s.StartedAt)
+			finished, ok2 := parseTime(s.FinishedAt)
+			if s.Name == nil || !ok1 || !ok2 {
+				continue
+			}
+			periods[i].stages[*s.Name] = append(periods[i].stages[*s.Name], finished.Sub(started))
+		}
+	}
+
+	report := &PerformanceReport{
+		RepoSlug:      repoSlug,
+		Branch:        o.Branch,
+		Since:         o.Since,
+		Until:         o.Until,
+		BaselineSince: o.BaselineSince,
+		BaselineUntil: o.BaselineUntil,
+		Current:       current.finish(),
+		Baseline:      baseline.finish(),
+	}
+
+	for name, durations := range current.stages {
+		report.Stages = append(report.Stages, &StagePerformance{
+			Name:                   name,
+			Runs:                   len(durations),
+			MedianDuration:         percentile(durations, 0.5),
+			BaselineMedianDuration: percentile(baseline.stages[name], 0.5),
+		})
+	}
+	sort.Slice(report.Stages, func(i, j int) bool {
+		a, b := report.Stages[i], report.Stages[j]
+		if a.MedianDuration != b.MedianDuration {
+			return a.MedianDuration > b.MedianDuration
+		}
+		return a.Name < b.Name
+	})
+
+	compare := func(metric string, base, cur time.Duration) {
+		if base <= 0 {
+			return
+		}
+		change := float64(cur-base) / float64(base)
+		if change >= o.RegressionThreshold {
+			report.Regressions = append(report.Regressions, &PerformanceRegression{Metric: metric, Baseline: base, Current: cur, Change: change})
+		}
+	}
+	compare("median_duration", report.Baseline.MedianDuration, report.Current.MedianDuration)
+	compare("p90_duration", report.Baseline.P90Duration, report.Current.P90Duration)
+	compare("median_queue_time", report.Baseline.MedianQueueTime, report.Current.MedianQueueTime)
+	for _, s := range report.Stages {
+		compare("stage:"+s.Name, s.BaselineMedianDuration, s.MedianDuration)
+	}
+	sort.SliceStable(report.Regressions, func(i, j int) bool {
+		return report.Regressions[i].Change > report.Regressions[j].Change
+	})
+
+	return report, resp, nil
+}
+
+func (p *performanceSamples) add(b *Build) {
+	p.stats.Builds++
+
+	if b.State != nil {
+		switch *b.State {
+		case BuildStatePassed:
+			p.stats.Passed++
+		case BuildStateFailed, BuildStateErrored:
+			p.stats.Failed++
+		}
+		if buildStateFinished(*b.State) && b.Duration != nil {
+			p.durations = append(p.durations, time.Duration(*b.Duration)*time.Second)
+		}
+	}
+
+	for _, j := range b.Jobs {
+		created, ok1 := parseTime(j.CreatedAt)
+		started, ok2 := parseTime(j.StartedAt)
+		if ok1 && ok2 && !started.Before(created) {
+			p.queueTimes = append(p.queueTimes, started.Sub(created))
+		}
+	}
+}
+
+func (p *performanceSamples) finish() *PerformanceStats {
+	stats := p.stats
+	if finished := stats.Passed + stats.Failed; finished > 0 {
+		stats.SuccessRate = float64(stats.Passed) / float64(finished)
+	}
+	stats.MedianDuration = percentile(p.durations, 0.5)
+	stats.P90Duration = percentile(p.durations, 0.9)
+	stats.MedianQueueTime = percentile(p.queueTimes, 0.5)
+	return &stats
+}
+
+// percentile returns the nearest-rank percentile p of durations, or 0 if there are none
+func percentile(durations []time.Duration, p float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+// WriteCSV writes the report as CSV, one metric per row with its baseline
+// and current values and the relative change. Durations are in seconds.
+func (r *PerformanceReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	formatFloat := func(f float64) string {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	row := func(metric string, base, cur float64) []string {
+		change := ""
+		if base != 0 {
+			change = strconv.FormatFloat((cur-base)/base, 'f', 4, 64)
+		}
+		return []string{metric, formatFloat(base), formatFloat(cur), change}
+	}
+
+	rows := [][]string{
+		{"metric", "baseline", "current", "change"},
+		row("builds", float64(r.Baseline.Builds), float64(r.Current.Builds)),
+		row("success_rate", r.Baseline.SuccessRate, r.Current.SuccessRate),
+		row("median_duration_seconds", r.Baseline.MedianDuration.Seconds(), r.Current.MedianDuration.Seconds()),
+		row("p90_duration_seconds", r.Baseline.P90Duration.Seconds(), r.Current.P90Duration.Seconds()),
+		row("median_queue_time_seconds", r.Baseline.MedianQueueTime.Seconds(), r.Current.MedianQueueTime.Seconds()),
+	}
+	for _, s := range r.Stages {
+		rows = append(rows, row("stage:"+s.Name+":median_duration_seconds", s.BaselineMedianDuration.Seconds(), s.MedianDuration.Seconds()))
+	}
+
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_AnalyzePerformance(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/builds", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		testFormValues(t, r, values{"branch.name": "master", "include": "build.jobs", "limit": "100", "sort_by": "id:desc"})
+		fmt.Fprint(w, `{"builds":[
+		  {"id":7,"state":"started","started_at":"2020-01-14T00:00:00Z"},
+		  {"id":6,"state":"passed","duration":200,"started_at":"2020-01-10T00:00:00Z","jobs":[{"created_at":"2020-01-10T00:00:00Z","started_at":"2020-01-10T00:01:00Z"}]},
+		  {"id":5,"state":"failed","duration":300,"started_at":"2020-01-09T00:00:00Z","jobs":[{"created_at":"2020-01-09T00:00:00Z","started_at":"2020-01-09T00:02:00Z"}]},
+		  {"id":8,"state":"canceled"},
+		  {"id":3,"state":"passed","duration":100,"started_at":"2020-01-05T00:00:00Z","jobs":[{"created_at":"2020-01-05T00:00:00Z","started_at":"2020-01-05T00:00:30Z"}]},
+		  {"id":2,"state":"passed","duration":100,"started_at":"2020-01-02T00:00:00Z"},
+		  {"id":1,"state":"passed","duration":100,"started_at":"2019-12-30T00:00:00Z"}
+		]}`)
+	})
+
+	stages := map[int]string{
+		6: `[{"name":"test","started_at":"2020-01-10T00:00:00Z","finished_at":"2020-01-10T00:02:30Z"}]`,
+		5: `[{"name":"test","started_at":"2020-01-09T00:00:00Z","finished_at":"2020-01-09T00:04:10Z"},{"name":"deploy","started_at":"2020-01-09T00:05:00Z","finished_at":"2020-01-09T00:05:10Z"}]`,
+		3: `[{"name":"test","started_at":"2020-01-05T00:00:00Z","finished_at":"2020-01-05T00:01:40Z"}]`,
+		2: `[{"name":"test","started_at":"2020-01-02T00:00:00Z","finished_at":"2020-01-02T00:01:20Z"}]`,
+	}
+	for id, body := range stages {
+		body := body
+		mux.HandleFunc(fmt.Sprintf("/build/%d/stages", id), func(w http.ResponseWriter, r *http.Request) {
+			testMethod(t, r, http.MethodGet)
+			fmt.Fprintf(w, `{"stages":%s}`, body)
+		})
+	}
+
+	opt := &PerformanceOption{
+		Branch: "master",
+		Since:  time.Date(2020, 1, 8, 0, 0, 0, 0, time.UTC),
+		Until:  time.Date(2020, 1, 15, 0, 0, 0, 0, time.UTC),
+	}
+	report, _, err := client.AnalyzePerformance(context.Background(), testRepoSlug, opt)
+
+	if err != nil {
+		t.Fatalf("Client.AnalyzePerformance returned error: %v", err)
+	}
+
+	if want := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC); !report.BaselineSince.Equal(want) {
+		t.Errorf("Client.AnalyzePerformance returned baseline since %v, want %v", report.BaselineSince, want)
+	}
+
+	wantCurrent := &PerformanceStats{Builds: 3, Passed: 1, Failed: 1, SuccessRate: 0.5, MedianDuration: 200 * time.Second, P90Duration: 300 * time.Second, MedianQueueTime: time.Minute}
+	if !reflect.DeepEqual(report.Current, wantCurrent) {
+		t.Errorf("Client.AnalyzePerformance returned current %+v, want %+v", report.Current, wantCurrent)
+	}
+	wantBaseline := &PerformanceStats{Builds: 2, Passed: 2, SuccessRate: 1, MedianDuration: 100 * time.Second, P90Duration: 100 * time.Second, MedianQueueTime: 30 * time.Second}
+	if !reflect.DeepEqual(report.Baseline, wantBaseline) {
+		t.Errorf("Client.AnalyzePerformance returned baseline %+v, want %+v", report.Baseline, wantBaseline)
+	}
+
+	wantStages := []*StagePerformance{
+		{Name: "test", Runs: 2, MedianDuration: 150 * time.Second, BaselineMedianDuration: 80 * time.Second},
+		{Name: "deploy", Runs: 1, MedianDuration: 10 * time.Second},
+	}
+	if !reflect.DeepEqual(report.Stages, wantStages) {
+		t.Errorf("Client.AnalyzePerformance returned stages %+v, want %+v", report.Stages, wantStages)
+	}
+
+	var regressions []string
+	for _, r := range report.Regressions {
+		regressions = append(regressions, fmt.Sprintf("%s %s->%s %.3f", r.Metric, r.Baseline, r.Current, r.Change))
+	}
+	wantRegressions := []string{
+		"p90_duration 1m40s->5m0s 2.000",
+		"median_duration 1m40s->3m20s 1.000",
+		"median_queue_time 30s->1m0s 1.000",
+		"stage:test 1m20s->2m30s 0.875",
+	}
+	if !reflect.DeepEqual(regressions, wantRegressions) {
+		t.Errorf("Client.AnalyzePerformance returned regressions %q, want %q", regressions, wantRegressions)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteCSV(&buf); err != nil {
+		t.Fatalf("PerformanceReport.WriteCSV returned error: %v", err)
+	}
+
+	wantCSV := `metric,baseline,current,change
+builds,2,3,0.5000
+success_rate,1,0.5,-0.5000
+median_duration_seconds,100,200,1.0000
+p90_duration_seconds,100,300,2.0000
+median_queue_time_seconds,30,60,1.0000
+stage:test:median_duration_seconds,80,150,0.8750
+stage:deploy:median_duration_seconds,0,10,
+`
+	if got := buf.String(); got != wantCSV {
+		t.Errorf("PerformanceReport.WriteCSV returned\n%s\nwant\n%s", got, wantCSV)
+	}
+}
+
+func TestPerformanceReport_json(t *testing.T) {
+	report := &PerformanceReport{
+		Current:     &PerformanceStats{Builds: 3, MedianDuration: 200 * time.Second, P90Duration: 300 * time.Second, MedianQueueTime: 1500 * time.Millisecond},
+		Stages:      []*StagePerformance{{Name: "test", Runs: 2, MedianDuration: 150 * time.Second, BaselineMedianDuration: 80 * time.Second}},
+		Regressions: []*PerformanceRegression{{Metric: "median_duration", Baseline: 100 * time.Second, Current: 200 * time.Second, Change: 1}},
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("json.Marshal returned error: %v", err)
+	}
+
+	for _, want := range []string{
+		`"current":{"builds":3,"passed":0,"failed":0,"success_rate":0,"median_duration_seconds":200,"p90_duration_seconds":300,"median_queue_time_seconds":1.5}`,
+		`"stages":[{"name":"test","runs":2,"median_duration_seconds":150,"baseline_median_duration_seconds":80}]`,
+		`"regressions":[{"metric":"median_duration","change":1,"baseline_seconds":100,"current_seconds":200}]`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("json.Marshal returned %s, want it to contain %s", data, want)
+		}
+	}
+
+	var decoded PerformanceReport
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal returned error: %v", err)
+	}
+	if !reflect.DeepEqual(decoded.Current, report.Current) || !reflect.DeepEqual(decoded.Stages, report.Stages) || !reflect.DeepEqual(decoded.Regressions, report.Regressions) {
+		t.Errorf("json.Unmarshal returned %+v, want %+v", decoded, report)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	durations := []time.Duration{5, 1, 4, 2, 3, 6, 7, 8, 9, 10}
+
+	cases := map[float64]time.Duration{0: 1, 0.5: 5, 0.9: 9, 1: 10}
+	for p, want := range cases {
+		if got := percentile(durations, p); got != want {
+			t.Errorf("percentile(%v) returned %v, want %v", p, got, want)
+		}
+	}
+
+	if got := percentile(nil, 0.5); got != 0 {
+		t.Errorf("percentile(nil) returned %v, want 0", got)
+	}
+}
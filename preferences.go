@@ -23,7 +23,7 @@ type PreferencesService struct {
 type Preference struct {
 	// The preference's name
 	Name *string `json:"name,omitempty"`
-	// The preference's value
+	// The preference's value, see BoolValue and IntValue
 	Value interface{} `json:"value"`
 	*Metadata
 }
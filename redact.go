@@ -0,0 +1,160 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// RedactedMask replaces the secrets masked by Redactor,
+// the same way Travis CI masks secure values in logs
+const RedactedMask = "[secure]"
+
+// minDerivedSecretLength is the length under which variants of a secret
+// are not masked, as they would match too much unrelated text
+const minDerivedSecretLength = 6
+
+// Redactor is an io.WriteCloser masking secrets in the text written through it
+// before writing it to the underlying writer.
+//
+// It masks the provided secrets, their base64 and URL encoded variants,
+// and well known kinds of credentials such as GitHub, AWS and Slack tokens.
+// Text is buffered until the end of each line so secrets split across writes
+// are still masked, so Close must be called to flush the last line.
+type Redactor struct {
+	w        io.Writer
+	replacer *strings.Replacer
+	buf      []byte
+}
+
+// NewRedactor returns a Redactor masking secrets in the text written to w.
+// secrets are typically the values of the private environment variables of a repository.
+func NewRedactor(w io.Writer, secrets ...string) *Redactor {
+	var variants []string
+	seen := map[string]bool{}
+	for _, secret := range secrets {
+		for _, v := range secretVariants(secret) {
+			if v != "" && !seen[v] {
+				seen[v] = true
+				variants = append(variants, v)
+			}
+		}
+	}
+
+	// The replacer prefers earlier arguments at the same position,
+	// so longer variants go first not to leave parts of them behind
+	sort.SliceStable(variants, func(i, j int) bool {
+		return len(variants[i]) > len(variants[j])
+	})
+
+	oldnew := make([]string, 0, 2*len(variants))
+	for _, v := range variants {
+		oldnew = append(oldnew, v, RedactedMask)
+	}
+
+	return &Redactor{w: w, replacer: strings.NewReplacer(oldnew...)}
+}
+
+// Redact masks secrets in s, see Redactor
+func Redact(s string, secrets ...string) string {
+	return NewRedactor(nil, secrets...).redact(s)
+}
+
+// Write masks and writes the complete lines of p,
+// and buffers the rest until the end of the line is written
+func (r *Redactor) Write(p []byte) (int, error) {
+	r.buf = append(r.buf, p...)
+
+	i := bytes.LastIndexByte(r.buf, '\n')
+	if i < 0 {
+		return len(p), nil
+	}
+
+	lines := string(r.buf[:i+1])
+	r.buf = append(r.buf[:0], r.buf[i+1:]...)
+
+	if _, err := io.WriteString(r.w, r.redact(lines)); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Close masks and writes the buffered text.
+// It does not close the underlying writer.
+func (r *Redactor) Close() error {
+	if len(r.buf) == 0 {
+		return nil
+	}
+
+	rest := string(r.buf)
+	r.buf = r.buf[:0]
+
+	_, err := io.WriteString(r.w, r.redact(rest))
+	return err
+}
+
+func (r *Redactor) redact(s string) string {
+	s = r.replacer.Replace(s)
+
+	for _, p := range secretValuePatterns {
+		s = p.pattern.ReplaceAllString(s, RedactedMask)
+	}
+
+	return s
+}
+
+// secretVariants returns the forms a secret may appear in a log: as is,
+// line by line for multiline secrets, URL encoded, and base64 encoded,
+// including inside a longer base64 encoded text such as a basic auth header
+func secretVariants(secret string) []string {
+	if secret == "" {
+		return nil
+	}
+
+	variants := []string{secret}
+	if strings.Contains(secret, "\n") {
+		for _, line := range strings.Split(secret, "\n") {
+			if line = strings.TrimSpace(line); len(line) >= minDerivedSecretLength {
+				variants = append(variants, line)
+			}
+		}
+	}
+
+	variants = append(variants, url.QueryEscape(secret), url.PathEscape(secret))
+
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding} {
+		variants = append(variants, encoding.EncodeToString([]byte(secret)))
+
+		// When the secret is not aligned on 3 bytes in the encoded text, its
+		// encoding differs at both ends, so only the part of the encoding
+		// which only depends on the secret is masked
+		for offset := 0; offset < 3; offset++ {
+			data := append(make([]byte, offset), secret...)
+			encoded := encoding.EncodeToString(data)
+
+			start := (offset*8 + 5) / 6
+			end := len(data) * 8 / 6
+			if end > start {
+				variants = append(variants, encoded[start:end])
+			}
+		}
+	}
+
+	derived := variants[:1]
+	for _, v := range variants[1:] {
+		if len(v) >= minDerivedSecretLength {
+			derived = append(derived, v)
+		}
+	}
+
+	return derived
+}
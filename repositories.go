@@ -72,8 +72,8 @@ type RepositoriesOption struct {
 	Limit int `url:"limit,omitempty"`
 	// How many repositories to skip before the first entry in the response
 	Offset int `url:"offset,omitempty"`
-	// Attributes to sort repositories by
-	SortBy string `url:"sort_by,omitempty"`
+	// Attributes to sort repositories by, e.g. SortOrder{SortAsc(RepositorySortFieldName)}
+	SortBy SortOrder `url:"sort_by,omitempty"`
 	// List of attributes to eager load
 	Include []string `url:"include,omitempty,comma"`
 }
@@ -294,3 +294,22 @@ func (rs *RepositoriesService) Unstar(ctx context.Context, slug string) (*Reposi
 
 	return &repository, resp, err
 }
+
+// listAllByOwner fetches all the repositories of the provided owner, page by page
+func (rs *RepositoriesService) listAllByOwner(ctx context.Context, owner string) ([]*Repository, *http.Response, error) {
+	var all []*Repository
+
+	opt := RepositoriesOption{Limit: 100}
+	for {
+		repos, resp, err := rs.ListByOwner(ctx, owner, &opt)
+		if err != nil {
+			return nil, resp, err
+		}
+
+		all = append(all, repos...)
+		if len(repos) < opt.Limit {
+			return all, resp, nil
+		}
+		opt.Offset += opt.Limit
+	}
+}
@@ -0,0 +1,252 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// DefaultPrivatePlaceholder is the value given to the private environment
+// variables created by CloneRepoConfig when no placeholder is specified
+const DefaultPrivatePlaceholder = "CHANGE_ME"
+
+// CloneRepoConfigOption specifies options for cloning the configuration of a repository
+type CloneRepoConfigOption struct {
+	// The value given to the copies of private environment variables,
+	// whose values cannot be read. Defaults to DefaultPrivatePlaceholder.
+	PrivatePlaceholder string
+}
+
+// CloneRepoConfigReport reports what CloneRepoConfig copied.
+// Environment variables scoped to a branch are named NAME@branch,
+// and crons are named after their branch.
+type CloneRepoConfigReport struct {
+	// The settings updated on the target repository
+	Settings []string `json:"settings"`
+	// The public environment variables copied with their values
+	EnvVars []string `json:"env_vars"`
+	// The private environment variables created with a placeholder value,
+	// which has to be replaced with the actual value
+	Placeholders []string `json:"placeholders"`
+	// The crons copied
+	Crons []string `json:"crons"`
+	// What could not be copied
+	Skipped []*CloneSkip `json:"skipped"`
+}
+
+// CloneSkip is something CloneRepoConfig could not copy
+type CloneSkip struct {
+	// The kind of resource: setting, env_var, cron or cache
+	Resource string `json:"resource"`
+	// The name of the resource
+	Name string `json:"name"`
+	// Why it was not copied
+	Reason string `json:"reason"`
+}
+
+// CloneRepoConfig copies the configuration of the repository fromSlug to the
+// repository toSlug: its settings, its public environment variables with their
+// values, its private environment variables with a placeholder value, and its crons.
+//
+// Environment variables are matched on name and branch. Private environment
+// variables which already exist on the target repository are left untouched. Caches are built by jobs and are never copied.
+// Crons replacing an existing one are created before it is deleted, so a branch
+// keeps its cron when the copy fails. Resources which fail to be copied, e.g. crons of branches missing from the
+// target repository, are reported as skipped instead of stopping the copy.
+func (c *Client) CloneRepoConfig(ctx context.Context, fromSlug string, toSlug string, opt *CloneRepoConfigOption) (*CloneRepoConfigReport, *http.Response, error) {
+	placeholder := DefaultPrivatePlaceholder
+	if opt != nil && opt.PrivatePlaceholder != "" {
+		placeholder = opt.PrivatePlaceholder
+	}
+
+	report := &CloneRepoConfigReport{}
+
+	resp, err := c.cloneSettings(ctx, fromSlug, toSlug, report)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	resp, err = c.cloneEnvVars(ctx, fromSlug, toSlug, placeholder, report)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	resp, err = c.cloneCrons(ctx, fromSlug, toSlug, report)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	caches, resp, err := c.Caches.ListByRepoSlug(ctx, fromSlug)
+	if err != nil {
+		return nil, resp, err
+	}
+	for _, cache := range caches {
+		name := ""
+		if cache.Branch != nil {
+			name = *cache.Branch
+		}
+		if cache.Match != nil {
+			name += "/" + *cache.Match
+		}
+		report.Skipped = append(report.Skipped, &CloneSkip{Resource: "cache", Name: name, Reason: "caches are built by jobs and cannot be copied"})
+	}
+
+	return report, resp, nil
+}
+
+func (c *Client) cloneSettings(ctx context.Context, fromSlug string, toSlug string, report *CloneRepoConfigReport) (*http.Response, error) {
+	from, resp, err := c.Settings.GetAllByRepoSlug(ctx, fromSlug)
+	if err != nil {
+		return resp, err
+	}
+
+	to, resp, err := c.Settings.GetAllByRepoSlug(ctx, toSlug)
+	if err != nil {
+		return resp, err
+	}
+
+	for _, setting := range to.Changes(from) {
+		if _, _, err := c.Settings.UpdateByRepoSlug(ctx, toSlug, setting); err != nil {
+			report.Skipped = append(report.Skipped, &CloneSkip{Resource: "setting", Name: setting.Name, Reason: err.Error()})
+			continue
+		}
+		report.Settings = append(report.Settings, setting.Name)
+	}
+
+	return resp, nil
+}
+
+func (c *Client) cloneEnvVars(ctx context.Context, fromSlug string, toSlug string, placeholder string, report *CloneRepoConfigReport) (*http.Response, error) {
+	from, resp, err := c.EnvVars.ListByRepoSlug(ctx, fromSlug)
+	if err != nil {
+		return resp, err
+	}
+
+	to, resp, err := c.EnvVars.ListByRepoSlug(ctx, toSlug)
+	if err != nil {
+		return resp, err
+	}
+
+	matched := map[*EnvVar]bool{}
+	for _, ev := range from {
+		if ev.Name == nil {
+			continue
+		}
+
+		body := &EnvVarBody{Name: *ev.Name, Public: ev.Public != nil && *ev.Public}
+		if ev.Branch != nil {
+			body.Branch = *ev.Branch
+		}
+		name := envVarDisplayName(body.Name, body.Branch)
+
+		existing := matchEnvVar(to, matched, body)
+		if !body.Public {
+			if existing != nil {
+				report.Skipped = append(report.Skipped, &CloneSkip{Resource: "env_var", Name: name, Reason: "private variable already exists in the target repository"})
+				continue
+			}
+
+			body.Value = placeholder
+			if _, _, err := c.EnvVars.CreateByRepoSlug(ctx, toSlug, body); err != nil {
+				report.Skipped = append(report.Skipped, &CloneSkip{Resource: "env_var", Name: name, Reason: err.Error()})
+				continue
+			}
+			report.Placeholders = append(report.Placeholders, name)
+			continue
+		}
+
+		if ev.Value != nil {
+			body.Value = *ev.Value
+		}
+
+		switch {
+		case existing == nil:
+			_, _, err = c.EnvVars.CreateByRepoSlug(ctx, toSlug, body)
+		case envVarUnchanged(existing, body):
+			continue
+		default:
+			_, _, err = c.EnvVars.UpdateByRepoSlug(ctx, toSlug, *existing.Id, body)
+		}
+		if err != nil {
+			report.Skipped = append(report.Skipped, &CloneSkip{Resource: "env_var", Name: name, Reason: err.Error()})
+			continue
+		}
+		report.EnvVars = append(report.EnvVars, name)
+	}
+
+	return resp, nil
+}
+
+func (c *Client) cloneCrons(ctx context.Context, fromSlug string, toSlug string, report *CloneRepoConfigReport) (*http.Response, error) {
+	from, resp, err := c.Crons.listAllByRepoSlug(ctx, fromSlug)
+	if err != nil {
+		return resp, err
+	}
+
+	to, resp, err := c.Crons.listAllByRepoSlug(ctx, toSlug)
+	if err != nil {
+		return resp, err
+	}
+
+	toByBranch := map[string]*Cron{}
+	for _, cron := range to {
+		if cron.Branch != nil && cron.Branch.Name != nil {
+			toByBranch[*cron.Branch.Name] = cron
+		}
+	}
+
+	for _, cron := range from {
+		if cron.Branch == nil || cron.Branch.Name == nil || cron.Interval == nil {
+			continue
+		}
+		branch := *cron.Branch.Name
+
+		body := &CronBody{Interval: *cron.Interval}
+		if cron.DontRunIfRecentBuildExists != nil {
+			body.DontRunIfRecentBuildExists = *cron.DontRunIfRecentBuildExists
+		}
+
+		existing := toByBranch[branch]
+		if existing != nil {
+			if existing.Interval != nil && *existing.Interval == body.Interval &&
+				existing.DontRunIfRecentBuildExists != nil && *existing.DontRunIfRecentBuildExists == body.DontRunIfRecentBuildExists {
+				continue
+			}
+			if existing.Id == nil {
+				report.Skipped = append(report.Skipped, &CloneSkip{Resource: "cron", Name: branch, Reason: "the existing cron of the target repository has no id and cannot be replaced"})
+				continue
+			}
+		}
+
+		// An existing cron is replaced, keeping it if the creation fails
+		var created *Cron
+		var resp *http.Response
+		var err error
+		if existing != nil {
+			created, resp, err = c.Crons.replaceByRepoSlug(ctx, toSlug, branch, *existing.Id, body)
+		} else {
+			created, resp, err = c.Crons.CreateByRepoSlug(ctx, toSlug, branch, body)
+		}
+		if err != nil {
+			reason := err.Error()
+			if created == nil {
+				if resp != nil && resp.StatusCode == http.StatusNotFound {
+					reason = fmt.Sprintf("branch %s does not exist in the target repository", branch)
+				}
+				if existing != nil {
+					reason += ", the existing cron was kept"
+				}
+			}
+			report.Skipped = append(report.Skipped, &CloneSkip{Resource: "cron", Name: branch, Reason: reason})
+			continue
+		}
+		report.Crons = append(report.Crons, branch)
+	}
+
+	return resp, nil
+}
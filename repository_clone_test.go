@@ -0,0 +1,194 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestClient_CloneRepoConfig(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	const from, to = "shuheiktgw/template", "shuheiktgw/service"
+
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/settings", from), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"settings":[{"name":"auto_cancel_pushes","value":true},{"name":"build_pushes","value":true},{"name":"maximum_number_of_builds","value":2}]}`)
+	})
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/settings", to), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"settings":[{"name":"auto_cancel_pushes","value":false},{"name":"build_pushes","value":true},{"name":"maximum_number_of_builds","value":0}]}`)
+	})
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/setting/auto_cancel_pushes", to), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPatch)
+		testBody(t, r, `{"setting.value":true}`+"\n")
+		fmt.Fprint(w, `{"name":"auto_cancel_pushes","value":true}`)
+	})
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/setting/maximum_number_of_builds", to), func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"error_type":"insufficient_access","error_message":"forbidden"}`)
+	})
+
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/env_vars", from), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"env_vars":[
+		  {"id":"1","name":"GO_VERSION","value":"1.14","public":true},
+		  {"id":"2","name":"API_TOKEN","public":false},
+		  {"id":"3","name":"DEPLOY_TOKEN","public":false,"branch":"master"}
+		]}`)
+	})
+	var created []EnvVarBody
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/env_vars", to), func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			var body EnvVarBody
+			json.NewDecoder(r.Body).Decode(&body)
+			created = append(created, body)
+			fmt.Fprint(w, `{"id":"10"}`)
+			return
+		}
+		fmt.Fprint(w, `{"env_vars":[
+		  {"id":"4","name":"API_TOKEN","public":false},
+		  {"id":"5","name":"GO_VERSION","value":"1.13","public":true,"branch":"develop"}
+		]}`)
+	})
+
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/crons", from), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"crons":[
+		  {"id":1,"branch":{"name":"master"},"interval":"daily","dont_run_if_recent_build_exists":true},
+		  {"id":2,"branch":{"name":"legacy"},"interval":"weekly","dont_run_if_recent_build_exists":false}
+		]}`)
+	})
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/crons", to), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"crons":[]}`)
+	})
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/branch/master/cron", to), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPost)
+		testBody(t, r, `{"cron.interval":"daily","cron.dont_run_if_recent_build_exists":true}`+"\n")
+		fmt.Fprint(w, `{"id":3}`)
+	})
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/branch/legacy/cron", to), func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error_type":"not_found","error_message":"branch not found"}`)
+	})
+
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/caches", from), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"caches":[{"branch":"master","match":"cache-linux"}]}`)
+	})
+
+	report, _, err := client.CloneRepoConfig(context.Background(), from, to, &CloneRepoConfigOption{PrivatePlaceholder: "TODO"})
+
+	if err != nil {
+		t.Fatalf("Client.CloneRepoConfig returned error: %v", err)
+	}
+
+	want := &CloneRepoConfigReport{
+		Settings:     []string{AutoCancelPushesSetting},
+		EnvVars:      []string{"GO_VERSION"},
+		Placeholders: []string{"DEPLOY_TOKEN@master"},
+		Crons:        []string{"master"},
+	}
+	if got := *report; !reflect.DeepEqual(got.Settings, want.Settings) ||
+		!reflect.DeepEqual(got.EnvVars, want.EnvVars) ||
+		!reflect.DeepEqual(got.Placeholders, want.Placeholders) ||
+		!reflect.DeepEqual(got.Crons, want.Crons) {
+		t.Errorf("Client.CloneRepoConfig returned %+v, want %+v", got, want)
+	}
+
+	var skipped []string
+	for _, s := range report.Skipped {
+		skipped = append(skipped, s.Resource+" "+s.Name)
+	}
+	wantSkipped := []string{"setting maximum_number_of_builds", "env_var API_TOKEN", "cron legacy", "cache master/cache-linux"}
+	if !reflect.DeepEqual(skipped, wantSkipped) {
+		t.Errorf("Client.CloneRepoConfig skipped %v, want %v", skipped, wantSkipped)
+	}
+
+	wantCreated := []EnvVarBody{
+		{Name: "GO_VERSION", Value: "1.14", Public: true},
+		{Name: "DEPLOY_TOKEN", Value: "TODO", Branch: "master"},
+	}
+	if !reflect.DeepEqual(created, wantCreated) {
+		t.Errorf("Client.CloneRepoConfig created %+v, want %+v", created, wantCreated)
+	}
+}
+
+func TestClient_CloneRepoConfig_replaceCrons(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	const from, to = "shuheiktgw/template", "shuheiktgw/service"
+
+	for _, slug := range []string{from, to} {
+		mux.HandleFunc(fmt.Sprintf("/repo/%s/settings", slug), func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"settings":[]}`)
+		})
+		mux.HandleFunc(fmt.Sprintf("/repo/%s/env_vars", slug), func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"env_vars":[]}`)
+		})
+	}
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/caches", from), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"caches":[]}`)
+	})
+
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/crons", from), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"crons":[
+		  {"id":1,"branch":{"name":"master"},"interval":"daily","dont_run_if_recent_build_exists":true},
+		  {"id":2,"branch":{"name":"legacy"},"interval":"weekly","dont_run_if_recent_build_exists":false},
+		  {"id":3,"branch":{"name":"develop"},"interval":"daily","dont_run_if_recent_build_exists":false}
+		]}`)
+	})
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/crons", to), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"crons":[
+		  {"id":7,"branch":{"name":"master"},"interval":"weekly","dont_run_if_recent_build_exists":true},
+		  {"id":8,"branch":{"name":"legacy"},"interval":"daily","dont_run_if_recent_build_exists":false},
+		  {"branch":{"name":"develop"},"interval":"weekly","dont_run_if_recent_build_exists":false}
+		]}`)
+	})
+
+	var requests []string
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/branch/master/cron", to), func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" master")
+		fmt.Fprint(w, `{"id":9}`)
+	})
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/branch/legacy/cron", to), func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" legacy")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"error_type":"insufficient_access","error_message":"forbidden"}`)
+	})
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/branch/develop/cron", to), func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" develop")
+		fmt.Fprint(w, `{"id":10}`)
+	})
+	mux.HandleFunc("/cron/", func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	report, _, err := client.CloneRepoConfig(context.Background(), from, to, nil)
+
+	if err != nil {
+		t.Fatalf("Client.CloneRepoConfig returned error: %v", err)
+	}
+
+	if want := []string{"master"}; !reflect.DeepEqual(report.Crons, want) {
+		t.Errorf("Client.CloneRepoConfig copied crons %v, want %v", report.Crons, want)
+	}
+
+	if len(report.Skipped) != 2 ||
+		report.Skipped[0].Name != "legacy" || !strings.HasSuffix(report.Skipped[0].Reason, "the existing cron was kept") ||
+		report.Skipped[1].Name != "develop" {
+		t.Errorf("Client.CloneRepoConfig skipped %+v", report.Skipped)
+	}
+
+	wantRequests := []string{"POST master", "DELETE /cron/7", "POST legacy"}
+	if !reflect.DeepEqual(requests, wantRequests) {
+		t.Errorf("Client.CloneRepoConfig made requests %v, want %v", requests, wantRequests)
+	}
+}
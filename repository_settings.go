@@ -0,0 +1,200 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"context"
+	"net/http"
+)
+
+// RepositorySettings represents the settings of a repository with typed values.
+// A nil field is a setting which is unknown, or left unchanged by Apply.
+type RepositorySettings struct {
+	BuildsOnlyWithTravisYml    *bool `json:"builds_only_with_travis_yml,omitempty"`
+	BuildPushes                *bool `json:"build_pushes,omitempty"`
+	BuildPullRequests          *bool `json:"build_pull_requests,omitempty"`
+	MaximumNumberOfBuilds      *int  `json:"maximum_number_of_builds,omitempty"`
+	AutoCancelPushes           *bool `json:"auto_cancel_pushes,omitempty"`
+	AutoCancelPullRequests     *bool `json:"auto_cancel_pull_requests,omitempty"`
+	AllowConfigImports         *bool `json:"allow_config_imports,omitempty"`
+	ShareEncryptedEnvWithForks *bool `json:"share_encrypted_env_with_forks,omitempty"`
+	ShareSshKeysWithForks      *bool `json:"share_ssh_keys_with_forks,omitempty"`
+	ConfigValidation           *bool `json:"config_validation,omitempty"`
+}
+
+const (
+	// AllowConfigImportsSetting is a setting name for allow_config_imports
+	AllowConfigImportsSetting = "allow_config_imports"
+	// ShareEncryptedEnvWithForksSetting is a setting name for share_encrypted_env_with_forks
+	ShareEncryptedEnvWithForksSetting = "share_encrypted_env_with_forks"
+	// ShareSshKeysWithForksSetting is a setting name for share_ssh_keys_with_forks
+	ShareSshKeysWithForksSetting = "share_ssh_keys_with_forks"
+	// ConfigValidationSetting is a setting name for config_validation
+	ConfigValidationSetting = "config_validation"
+)
+
+// settingField binds a setting name to a field of RepositorySettings,
+// which is either a **bool or a **int
+type settingField struct {
+	name  string
+	field interface{}
+}
+
+func (rs *RepositorySettings) fields() []settingField {
+	return []settingField{
+		{BuildsOnlyWithTravisYmlSetting, &rs.BuildsOnlyWithTravisYml},
+		{BuildPushesSetting, &rs.BuildPushes},
+		{BuildPullRequestsSetting, &rs.BuildPullRequests},
+		{MaximumNumberOfBuildsSetting, &rs.MaximumNumberOfBuilds},
+		{AutoCancelPushesSetting, &rs.AutoCancelPushes},
+		{AutoCancelPullRequestsSetting, &rs.AutoCancelPullRequests},
+		{AllowConfigImportsSetting, &rs.AllowConfigImports},
+		{ShareEncryptedEnvWithForksSetting, &rs.ShareEncryptedEnvWithForks},
+		{ShareSshKeysWithForksSetting, &rs.ShareSshKeysWithForks},
+		{ConfigValidationSetting, &rs.ConfigValidation},
+	}
+}
+
+// NewRepositorySettings converts a list of settings into RepositorySettings.
+// Settings with an unknown name or an unexpected type of value are ignored.
+func NewRepositorySettings(settings []*Setting) *RepositorySettings {
+	rs := &RepositorySettings{}
+	fields := rs.fields()
+
+	for _, s := range settings {
+		if s == nil || s.Name == nil {
+			continue
+		}
+
+		for _, f := range fields {
+			if f.name != *s.Name {
+				continue
+			}
+
+			switch field := f.field.(type) {
+			case **bool:
+				if v, ok := s.BoolValue(); ok {
+					*field = &v
+				}
+			case **int:
+				if v, ok := s.IntValue(); ok {
+					*field = &v
+				}
+			}
+		}
+	}
+
+	return rs
+}
+
+// Changes returns the settings to update to turn rs into desired.
+// Fields which are nil in desired are left unchanged.
+func (rs *RepositorySettings) Changes(desired *RepositorySettings) []*SettingBody {
+	var changes []*SettingBody
+
+	current := rs.fields()
+	for i, f := range desired.fields() {
+		switch field := f.field.(type) {
+		case **bool:
+			c := *current[i].field.(**bool)
+			if *field != nil && (c == nil || *c != **field) {
+				changes = append(changes, &SettingBody{Name: f.name, Value: **field})
+			}
+		case **int:
+			c := *current[i].field.(**int)
+			if *field != nil && (c == nil || *c != **field) {
+				changes = append(changes, &SettingBody{Name: f.name, Value: **field})
+			}
+		}
+	}
+
+	return changes
+}
+
+// GetAllByRepoId fetches the settings of given repository id
+//
+// Travis CI API docs: https://developer.travis-ci.com/resource/settings#for_repository
+func (ss *SettingsService) GetAllByRepoId(ctx context.Context, repoId uint) (*RepositorySettings, *http.Response, error) {
+	settings, resp, err := ss.ListByRepoId(ctx, repoId)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return NewRepositorySettings(settings), resp, err
+}
+
+// GetAllByRepoSlug fetches the settings of given repository slug
+//
+// Travis CI API docs: https://developer.travis-ci.com/resource/settings#for_repository
+func (ss *SettingsService) GetAllByRepoSlug(ctx context.Context, repoSlug string) (*RepositorySettings, *http.Response, error) {
+	settings, resp, err := ss.ListByRepoSlug(ctx, repoSlug)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return NewRepositorySettings(settings), resp, err
+}
+
+// ApplyByRepoId updates the settings of given repository id which differ from
+// the non-nil fields of settings, and returns the resulting settings
+//
+// Travis CI API docs: https://developer.travis-ci.com/resource/setting#update
+func (ss *SettingsService) ApplyByRepoId(ctx context.Context, repoId uint, settings *RepositorySettings) (*RepositorySettings, *http.Response, error) {
+	current, resp, err := ss.GetAllByRepoId(ctx, repoId)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	for _, change := range current.Changes(settings) {
+		if _, resp, err = ss.UpdateByRepoId(ctx, repoId, change); err != nil {
+			return nil, resp, err
+		}
+	}
+
+	return current.merge(settings), resp, nil
+}
+
+// ApplyByRepoSlug updates the settings of given repository slug which differ from
+// the non-nil fields of settings, and returns the resulting settings
+//
+// Travis CI API docs: https://developer.travis-ci.com/resource/setting#update
+func (ss *SettingsService) ApplyByRepoSlug(ctx context.Context, repoSlug string, settings *RepositorySettings) (*RepositorySettings, *http.Response, error) {
+	current, resp, err := ss.GetAllByRepoSlug(ctx, repoSlug)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	for _, change := range current.Changes(settings) {
+		if _, resp, err = ss.UpdateByRepoSlug(ctx, repoSlug, change); err != nil {
+			return nil, resp, err
+		}
+	}
+
+	return current.merge(settings), resp, nil
+}
+
+// merge returns a copy of rs with the non-nil fields of other
+func (rs *RepositorySettings) merge(other *RepositorySettings) *RepositorySettings {
+	merged := *rs
+
+	fields := merged.fields()
+	for i, f := range other.fields() {
+		switch field := f.field.(type) {
+		case **bool:
+			if *field != nil {
+				v := **field
+				*fields[i].field.(**bool) = &v
+			}
+		case **int:
+			if *field != nil {
+				v := **field
+				*fields[i].field.(**int) = &v
+			}
+		}
+	}
+
+	return &merged
+}
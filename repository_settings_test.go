@@ -0,0 +1,129 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testSettings = `{"settings":[
+  {"name":"builds_only_with_travis_yml","value":false},
+  {"name":"build_pushes","value":true},
+  {"name":"build_pull_requests","value":true},
+  {"name":"maximum_number_of_builds","value":0},
+  {"name":"auto_cancel_pushes","value":false},
+  {"name":"auto_cancel_pull_requests","value":false},
+  {"name":"allow_config_imports","value":false},
+  {"name":"share_encrypted_env_with_forks","value":false},
+  {"name":"share_ssh_keys_with_forks","value":true},
+  {"name":"config_validation","value":true},
+  {"name":"unknown_setting","value":true}
+]}`
+
+func TestSettingsService_GetAllByRepoSlug(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/settings", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, testSettings)
+	})
+
+	settings, _, err := client.Settings.GetAllByRepoSlug(context.Background(), testRepoSlug)
+
+	if err != nil {
+		t.Fatalf("Settings.GetAllByRepoSlug returned error: %v", err)
+	}
+
+	want := &RepositorySettings{
+		BuildsOnlyWithTravisYml:    Bool(false),
+		BuildPushes:                Bool(true),
+		BuildPullRequests:          Bool(true),
+		MaximumNumberOfBuilds:      Int(0),
+		AutoCancelPushes:           Bool(false),
+		AutoCancelPullRequests:     Bool(false),
+		AllowConfigImports:         Bool(false),
+		ShareEncryptedEnvWithForks: Bool(false),
+		ShareSshKeysWithForks:      Bool(true),
+		ConfigValidation:           Bool(true),
+	}
+	if !reflect.DeepEqual(settings, want) {
+		t.Errorf("Settings.GetAllByRepoSlug returned %+v, want %+v", settings, want)
+	}
+}
+
+func TestSettingsService_ApplyByRepoSlug(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/settings", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testSettings)
+	})
+
+	updated := map[string]interface{}{}
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/setting/", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodPatch)
+
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		name := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		updated[name] = body["setting.value"]
+
+		fmt.Fprintf(w, `{"name":"%s","value":%v}`, name, body["setting.value"])
+	})
+
+	settings, _, err := client.Settings.ApplyByRepoSlug(context.Background(), testRepoSlug, &RepositorySettings{
+		BuildPushes:           Bool(true),
+		MaximumNumberOfBuilds: Int(3),
+		AutoCancelPushes:      Bool(true),
+	})
+
+	if err != nil {
+		t.Fatalf("Settings.ApplyByRepoSlug returned error: %v", err)
+	}
+
+	want := map[string]interface{}{
+		MaximumNumberOfBuildsSetting: float64(3),
+		AutoCancelPushesSetting:      true,
+	}
+	if !reflect.DeepEqual(updated, want) {
+		t.Errorf("Settings.ApplyByRepoSlug updated %v, want %v", updated, want)
+	}
+
+	if *settings.MaximumNumberOfBuilds != 3 || !*settings.AutoCancelPushes || *settings.AutoCancelPullRequests {
+		t.Errorf("Settings.ApplyByRepoSlug returned %+v", settings)
+	}
+}
+
+func TestRepositorySettings_Changes(t *testing.T) {
+	current := &RepositorySettings{BuildPushes: Bool(true), MaximumNumberOfBuilds: Int(1)}
+
+	cases := []struct {
+		desired *RepositorySettings
+		want    []*SettingBody
+	}{
+		{&RepositorySettings{}, nil},
+		{&RepositorySettings{BuildPushes: Bool(true), MaximumNumberOfBuilds: Int(1)}, nil},
+		{&RepositorySettings{BuildPushes: Bool(false)}, []*SettingBody{{Name: BuildPushesSetting, Value: false}}},
+		{&RepositorySettings{ConfigValidation: Bool(true)}, []*SettingBody{{Name: ConfigValidationSetting, Value: true}}},
+		{&RepositorySettings{MaximumNumberOfBuilds: Int(2)}, []*SettingBody{{Name: MaximumNumberOfBuildsSetting, Value: 2}}},
+	}
+
+	for i, c := range cases {
+		if got := current.Changes(c.desired); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("#%d RepositorySettings.Changes returned %+v, want %+v", i, got, c.want)
+		}
+	}
+}
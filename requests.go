@@ -79,6 +79,8 @@ type RequestBody struct {
 	Message string `json:"message,omitempty"`
 	// Branch requested to be built
 	Branch string `json:"branch,omitempty"`
+	// Commit sha requested to be built, the head of Branch when empty
+	Sha string `json:"sha,omitempty"`
 	// Travis token associated with webhook on GitHub (DEPRECATED)
 	Token string `json:"token,omitempty"`
 }
@@ -0,0 +1,180 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+var (
+	resourceTypesMu sync.RWMutex
+
+	// resourceTypes maps the @type of a resource
+	// to a function allocating the Go type it decodes into
+	resourceTypes = map[string]func() interface{}{
+		"branch":       func() interface{} { return &Branch{} },
+		"build":        func() interface{} { return &Build{} },
+		"commit":       func() interface{} { return &Commit{} },
+		"cron":         func() interface{} { return &Cron{} },
+		"env_var":      func() interface{} { return &EnvVar{} },
+		"installation": func() interface{} { return &Installation{} },
+		"job":          func() interface{} { return &Job{} },
+		"key_pair":     func() interface{} { return &KeyPair{} },
+		"organization": func() interface{} { return &Organization{} },
+		"repository":   func() interface{} { return &Repository{} },
+		"request":      func() interface{} { return &Request{} },
+		"setting":      func() interface{} { return &Setting{} },
+		"stage":        func() interface{} { return &Stage{} },
+		"user":         func() interface{} { return &User{} },
+	}
+)
+
+// UnknownResourceTypeError is returned when a resource
+// with an unregistered @type is decoded
+type UnknownResourceTypeError struct {
+	// The @type of the resource, empty if it has none
+	Type string
+}
+
+func (e *UnknownResourceTypeError) Error() string {
+	if e.Type == "" {
+		return "travis: the resource has no @type"
+	}
+
+	return fmt.Sprintf("travis: unknown resource type %q", e.Type)
+}
+
+// RegisterResourceType registers the Go type resources of the given @type
+// are decoded into by DecodeResource. newValue must return a pointer to
+// a newly allocated value each time it is called.
+// Registering an already registered @type replaces it.
+func RegisterResourceType(typ string, newValue func() interface{}) {
+	resourceTypesMu.Lock()
+	defer resourceTypesMu.Unlock()
+
+	resourceTypes[typ] = newValue
+}
+
+// DecodeResource decodes a JSON resource into the Go type registered for
+// its @type, e.g. *User for "user" or *Organization for "organization",
+// so the result can be inspected with a type switch.
+func DecodeResource(data []byte) (interface{}, error) {
+	var m Metadata
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+
+	typ := ""
+	if m.Type != nil {
+		typ = *m.Type
+	}
+
+	resourceTypesMu.RLock()
+	newValue, ok := resourceTypes[typ]
+	resourceTypesMu.RUnlock()
+
+	if !ok {
+		return nil, &UnknownResourceTypeError{Type: typ}
+	}
+
+	v := newValue()
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// decodeResourceFrom re-encodes an already decoded value
+// and decodes it into the Go type registered for its @type
+func decodeResourceFrom(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeResource(data)
+}
+
+// Resource returns the owner as a *User or an *Organization
+// depending on its @type.
+//
+// The owner is converted from its decoded fields, so only the fields of
+// Owner are set on the result; the fields specific to users or organizations
+// are not. Fetch the user or the organization by id to get all of them.
+func (o *Owner) Resource() (interface{}, error) {
+	if o == nil {
+		return nil, nil
+	}
+
+	return decodeResourceFrom(o)
+}
+
+// RecipientResource returns the recipient of the broadcast as a *User,
+// an *Organization or a *Repository depending on its @type.
+// It returns nil for global broadcasts, which have no recipient.
+func (b *Broadcast) RecipientResource() (interface{}, error) {
+	if b.Recipient == nil {
+		return nil, nil
+	}
+
+	return decodeResourceFrom(b.Recipient)
+}
+
+// BoolValue returns the value of the setting if it is a boolean
+func (s *Setting) BoolValue() (bool, bool) {
+	return boolValue(s.Value)
+}
+
+// IntValue returns the value of the setting if it is an integer
+func (s *Setting) IntValue() (int, bool) {
+	return intValue(s.Value)
+}
+
+// BoolValue returns the value of the preference if it is a boolean
+func (p *Preference) BoolValue() (bool, bool) {
+	return boolValue(p.Value)
+}
+
+// IntValue returns the value of the preference if it is an integer
+func (p *Preference) IntValue() (int, bool) {
+	return intValue(p.Value)
+}
+
+// DecodeArgs decodes the args of the message into the value pointed to by v
+func (m *Message) DecodeArgs(v interface{}) error {
+	if len(m.Args) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(m.Args, v)
+}
+
+func boolValue(v interface{}) (bool, bool) {
+	b, ok := v.(bool)
+	return b, ok
+}
+
+func intValue(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case float64:
+		// encoding/json decodes numbers in interface{} values as float64
+		if n == float64(int(n)) {
+			return int(n), true
+		}
+	case json.Number:
+		i, err := n.Int64()
+		if err == nil {
+			return int(i), true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,181 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeResource(t *testing.T) {
+	cases := []struct {
+		data string
+		want interface{}
+	}{
+		{
+			`{"@type":"user","id":1,"login":"shuheiktgw"}`,
+			&User{Id: Uint(1), Login: String("shuheiktgw"), Metadata: &Metadata{Type: String("user")}},
+		},
+		{
+			`{"@type":"organization","id":2,"login":"travis-ci"}`,
+			&Organization{Id: Uint(2), Login: String("travis-ci"), Metadata: &Metadata{Type: String("organization")}},
+		},
+		{
+			`{"@type":"repository","id":3,"slug":"shuheiktgw/go-travis"}`,
+			&Repository{Id: Uint(3), Slug: String("shuheiktgw/go-travis"), Metadata: &Metadata{Type: String("repository")}},
+		},
+	}
+
+	for i, c := range cases {
+		got, err := DecodeResource([]byte(c.data))
+		if err != nil {
+			t.Fatalf("#%d DecodeResource returned error: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("#%d DecodeResource returned %+v, want %+v", i, got, c.want)
+		}
+	}
+}
+
+func TestDecodeResource_unknownType(t *testing.T) {
+	cases := []struct {
+		data string
+		want error
+	}{
+		{`{"@type":"unicorn","id":1}`, &UnknownResourceTypeError{Type: "unicorn"}},
+		{`{"id":1}`, &UnknownResourceTypeError{}},
+	}
+
+	for i, c := range cases {
+		_, err := DecodeResource([]byte(c.data))
+		if !reflect.DeepEqual(err, c.want) {
+			t.Errorf("#%d DecodeResource returned error %v, want %v", i, err, c.want)
+		}
+	}
+}
+
+func TestRegisterResourceType(t *testing.T) {
+	type unicorn struct {
+		Id   uint   `json:"id"`
+		Name string `json:"name"`
+	}
+
+	RegisterResourceType("unicorn", func() interface{} { return &unicorn{} })
+	defer func() {
+		resourceTypesMu.Lock()
+		delete(resourceTypes, "unicorn")
+		resourceTypesMu.Unlock()
+	}()
+
+	got, err := DecodeResource([]byte(`{"@type":"unicorn","id":1,"name":"sparkle"}`))
+	if err != nil {
+		t.Fatalf("DecodeResource returned error: %v", err)
+	}
+
+	want := &unicorn{Id: 1, Name: "sparkle"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DecodeResource returned %+v, want %+v", got, want)
+	}
+}
+
+func TestOwner_Resource(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc(fmt.Sprintf("/build/%d", testBuildId), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, `{"id":1,"created_by":{"@type":"user","@href":"/user/1","@representation":"minimal","id":1,"login":"shuheiktgw"}}`)
+	})
+
+	build, _, err := client.Builds.Find(context.Background(), testBuildId, nil)
+	if err != nil {
+		t.Fatalf("Builds.Find returned error: %v", err)
+	}
+
+	resource, err := build.CreatedBy.Resource()
+	if err != nil {
+		t.Fatalf("Owner.Resource returned error: %v", err)
+	}
+
+	switch user := resource.(type) {
+	case *User:
+		if *user.Login != "shuheiktgw" {
+			t.Errorf("Owner.Resource returned user %s, want shuheiktgw", *user.Login)
+		}
+	default:
+		t.Errorf("Owner.Resource returned %T, want *User", resource)
+	}
+}
+
+func TestBroadcast_RecipientResource(t *testing.T) {
+	var broadcast Broadcast
+	data := `{"id":1,"recipient":{"@type":"organization","@representation":"minimal","id":2,"login":"travis-ci"}}`
+	if err := json.Unmarshal([]byte(data), &broadcast); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := broadcast.RecipientResource()
+	if err != nil {
+		t.Fatalf("Broadcast.RecipientResource returned error: %v", err)
+	}
+
+	want := &Organization{Id: Uint(2), Login: String("travis-ci"), Metadata: &Metadata{Type: String("organization"), Representation: String(minimalRepresentation)}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Broadcast.RecipientResource returned %+v, want %+v", got, want)
+	}
+
+	global := Broadcast{Id: Uint(3)}
+	if got, err := global.RecipientResource(); got != nil || err != nil {
+		t.Errorf("Broadcast.RecipientResource returned %v, %v, want nil, nil", got, err)
+	}
+}
+
+func TestSetting_Value(t *testing.T) {
+	var settings []*Setting
+	data := `[{"name":"build_pushes","value":true},{"name":"maximum_number_of_builds","value":3}]`
+	if err := json.Unmarshal([]byte(data), &settings); err != nil {
+		t.Fatal(err)
+	}
+
+	if b, ok := settings[0].BoolValue(); !ok || !b {
+		t.Errorf("Setting.BoolValue returned %v, %v, want true, true", b, ok)
+	}
+	if _, ok := settings[0].IntValue(); ok {
+		t.Errorf("Setting.IntValue returned ok for a boolean setting")
+	}
+	if i, ok := settings[1].IntValue(); !ok || i != 3 {
+		t.Errorf("Setting.IntValue returned %v, %v, want 3, true", i, ok)
+	}
+	if _, ok := settings[1].BoolValue(); ok {
+		t.Errorf("Setting.BoolValue returned ok for an integer setting")
+	}
+}
+
+func TestPreference_Value(t *testing.T) {
+	p := Preference{Name: String("builds_email"), Value: false}
+
+	if b, ok := p.BoolValue(); !ok || b {
+		t.Errorf("Preference.BoolValue returned %v, %v, want false, true", b, ok)
+	}
+}
+
+func TestMessage_DecodeArgs(t *testing.T) {
+	m := Message{Args: json.RawMessage(`{"key":"language","value":"go"}`)}
+
+	var args map[string]string
+	if err := m.DecodeArgs(&args); err != nil {
+		t.Fatalf("Message.DecodeArgs returned error: %v", err)
+	}
+
+	want := map[string]string{"key": "language", "value": "go"}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("Message.DecodeArgs returned %+v, want %+v", args, want)
+	}
+}
@@ -0,0 +1,296 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SecretAuditOption specifies options for auditing secrets
+type SecretAuditOption struct {
+	// Minimum Shannon entropy, in bits per character, for the value of a public
+	// environment variable to look random. Defaults to 3.5.
+	EntropyThreshold float64
+	// Minimum number of repositories sharing a private environment variable
+	// for it to be reported as duplicated. Defaults to 3.
+	DuplicateThreshold int
+}
+
+// SecretSeverity is the severity of a secret audit finding
+type SecretSeverity string
+
+// Severities of secret audit findings
+const (
+	SecretSeverityHigh   SecretSeverity = "high"
+	SecretSeverityMedium SecretSeverity = "medium"
+	SecretSeverityLow    SecretSeverity = "low"
+)
+
+// SecretFindingKind is the kind of a secret audit finding
+type SecretFindingKind string
+
+const (
+	// SecretFindingPublicValue is a public environment variable whose value looks like a secret
+	SecretFindingPublicValue SecretFindingKind = "public_secret_value"
+	// SecretFindingPublicName is a public environment variable whose name looks like a secret
+	SecretFindingPublicName SecretFindingKind = "public_secret_name"
+	// SecretFindingSharedWithForks is a repository sharing its encrypted variables with forks
+	SecretFindingSharedWithForks SecretFindingKind = "share_encrypted_env_with_forks"
+	// SecretFindingNoCustomKeyPair is a repository without a custom key pair
+	SecretFindingNoCustomKeyPair SecretFindingKind = "no_custom_key_pair"
+	// SecretFindingDuplicatedPrivate is a private environment variable defined in many repositories
+	SecretFindingDuplicatedPrivate SecretFindingKind = "duplicated_private_env_var"
+)
+
+// SecretFinding is a risk found by AuditSecrets
+type SecretFinding struct {
+	Kind     SecretFindingKind `json:"kind"`
+	Severity SecretSeverity    `json:"severity"`
+	// The slugs of the repositories concerned
+	Repositories []string `json:"repositories"`
+	// The name of the environment variable concerned, if any
+	EnvVar string `json:"env_var,omitempty"`
+	// A human readable description of the finding
+	Message string `json:"message"`
+}
+
+// SecretAuditReport is the result of AuditSecrets.
+// It is encoded to JSON with encoding/json, or to Markdown with Markdown.
+type SecretAuditReport struct {
+	// The owner whose repositories were audited
+	Owner string `json:"owner"`
+	// The number of repositories audited
+	Repositories int `json:"repositories"`
+	// The findings, most severe first
+	Findings []*SecretFinding `json:"findings"`
+}
+
+// secretValuePatterns detect well known kinds of credentials
+var secretValuePatterns = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"an AWS access key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"a GitHub token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+	{"a Slack token", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`)},
+	{"a Google API key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"a Stripe secret key", regexp.MustCompile(`\b[rs]k_live_[0-9A-Za-z]{16,}\b`)},
+	{"an npm token", regexp.MustCompile(`\bnpm_[A-Za-z0-9]{36}\b`)},
+	{"a private key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+	{"a URL with credentials", regexp.MustCompile(`[a-z][a-z0-9+.-]*://[^/\s:@]+:[^/\s:@]+@`)},
+}
+
+var (
+	tokenPattern       = regexp.MustCompile(`^[A-Za-z0-9+/=_-]{20,}$`)
+	tokenLetterPattern = regexp.MustCompile(`[A-Za-z]`)
+	tokenDigitPattern  = regexp.MustCompile(`[0-9]`)
+)
+
+// looksLikeToken reports whether s is made of the characters of encoded tokens,
+// mixing letters and digits, which rules out words, versions, paths and URLs
+func looksLikeToken(s string) bool {
+	return tokenPattern.MatchString(s) && tokenLetterPattern.MatchString(s) && tokenDigitPattern.MatchString(s)
+}
+
+var secretNamePattern = regexp.MustCompile(`(?i)(secret|token|passw(or)?d|pwd|api_?key|access_?key|private_?key|credential|auth)`)
+
+// AuditSecrets walks all the repositories of the provided owner and reports
+// the risks found in their secrets:
+// public environment variables whose values look like credentials, by pattern
+// or entropy, or whose names look like they hold one, repositories sharing
+// their encrypted variables with forks, repositories without a custom key pair,
+// and private environment variables defined in many repositories.
+func (c *Client) AuditSecrets(ctx context.Context, owner string, opt *SecretAuditOption) (*SecretAuditReport, *http.Response, error) {
+	entropyThreshold, duplicateThreshold := 3.5, 3
+	if opt != nil && opt.EntropyThreshold > 0 {
+		entropyThreshold = opt.EntropyThreshold
+	}
+	if opt != nil && opt.DuplicateThreshold > 0 {
+		duplicateThreshold = opt.DuplicateThreshold
+	}
+
+	repos, resp, err := c.Repositories.listAllByOwner(ctx, owner)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	report := &SecretAuditReport{Owner: owner}
+	privateVars := map[string][]string{}
+
+	for _, repo := range repos {
+		if repo.Id == nil || repo.Slug == nil {
+			continue
+		}
+		report.Repositories++
+		slug := *repo.Slug
+
+		envVars, resp, err := c.EnvVars.ListByRepoId(ctx, *repo.Id)
+		if err != nil {
+			return nil, resp, err
+		}
+
+		for _, ev := range envVars {
+			if ev.Name == nil {
+				continue
+			}
+
+			if ev.Public == nil || !*ev.Public {
+				privateVars[*ev.Name] = append(privateVars[*ev.Name], slug)
+				continue
+			}
+
+			if f := auditPublicEnvVar(slug, *ev.Name, ev.Value, entropyThreshold); f != nil {
+				report.Findings = append(report.Findings, f)
+			}
+		}
+
+		settings, resp, err := c.Settings.GetAllByRepoId(ctx, *repo.Id)
+		if err != nil {
+			return nil, resp, err
+		}
+		if settings.ShareEncryptedEnvWithForks != nil && *settings.ShareEncryptedEnvWithForks {
+			report.Findings = append(report.Findings, &SecretFinding{
+				Kind:         SecretFindingSharedWithForks,
+				Severity:     SecretSeverityHigh,
+				Repositories: []string{slug},
+				Message:      "Encrypted environment variables are shared with pull requests from forks",
+			})
+		}
+
+		_, resp, err = c.KeyPair.FindByRepoId(ctx, *repo.Id)
+		switch {
+		case resp != nil && resp.StatusCode == http.StatusNotFound:
+			report.Findings = append(report.Findings, &SecretFinding{
+				Kind:         SecretFindingNoCustomKeyPair,
+				Severity:     SecretSeverityLow,
+				Repositories: []string{slug},
+				Message:      "No custom key pair, builds clone with the default key",
+			})
+		case err != nil:
+			return nil, resp, err
+		}
+	}
+
+	names := make([]string, 0, len(privateVars))
+	for name := range privateVars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		slugs := uniqueStrings(privateVars[name])
+		if len(slugs) < duplicateThreshold {
+			continue
+		}
+		report.Findings = append(report.Findings, &SecretFinding{
+			Kind:         SecretFindingDuplicatedPrivate,
+			Severity:     SecretSeverityMedium,
+			Repositories: slugs,
+			EnvVar:       name,
+			Message:      fmt.Sprintf("Private variable defined in %d repositories, consider a narrower credential per repository", len(slugs)),
+		})
+	}
+
+	severities := map[SecretSeverity]int{SecretSeverityHigh: 0, SecretSeverityMedium: 1, SecretSeverityLow: 2}
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		return severities[report.Findings[i].Severity] < severities[report.Findings[j].Severity]
+	})
+
+	return report, resp, nil
+}
+
+func auditPublicEnvVar(slug string, name string, value *string, entropyThreshold float64) *SecretFinding {
+	finding := &SecretFinding{Repositories: []string{slug}, EnvVar: name}
+
+	if value != nil {
+		for _, p := range secretValuePatterns {
+			if p.pattern.MatchString(*value) {
+				finding.Kind = SecretFindingPublicValue
+				finding.Severity = SecretSeverityHigh
+				finding.Message = fmt.Sprintf("Public variable looks like %s", p.name)
+				return finding
+			}
+		}
+
+		if looksLikeToken(*value) && shannonEntropy(*value) >= entropyThreshold {
+			finding.Kind = SecretFindingPublicValue
+			finding.Severity = SecretSeverityHigh
+			finding.Message = "Public variable has a random looking value"
+			return finding
+		}
+	}
+
+	if secretNamePattern.MatchString(name) {
+		finding.Kind = SecretFindingPublicName
+		finding.Severity = SecretSeverityMedium
+		finding.Message = "Public variable is named like a secret"
+		return finding
+	}
+
+	return nil
+}
+
+// shannonEntropy returns the Shannon entropy of s in bits per character
+func shannonEntropy(s string) float64 {
+	counts := map[rune]int{}
+	n := 0
+	for _, r := range s {
+		counts[r]++
+		n++
+	}
+
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(n)
+		entropy -= p * math.Log2(p)
+	}
+
+	return entropy
+}
+
+func uniqueStrings(ss []string) []string {
+	seen := map[string]bool{}
+	var unique []string
+	for _, s := range ss {
+		if !seen[s] {
+			seen[s] = true
+			unique = append(unique, s)
+		}
+	}
+	return unique
+}
+
+// Markdown returns the report as a Markdown document
+func (r *SecretAuditReport) Markdown() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Secret audit of %s\n\n", r.Owner)
+	fmt.Fprintf(&b, "Audited %d repositories, found %d issues.\n", r.Repositories, len(r.Findings))
+
+	if len(r.Findings) == 0 {
+		return b.String()
+	}
+
+	escape := strings.NewReplacer("|", `\|`, "\n", " ").Replace
+
+	b.WriteString("\n| Severity | Repositories | Variable | Finding |\n")
+	b.WriteString("| --- | --- | --- | --- |\n")
+	for _, f := range r.Findings {
+		envVar := ""
+		if f.EnvVar != "" {
+			envVar = "`" + escape(f.EnvVar) + "`"
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", f.Severity, escape(strings.Join(f.Repositories, ", ")), envVar, escape(f.Message))
+	}
+
+	return b.String()
+}
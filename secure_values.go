@@ -0,0 +1,215 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"regexp"
+)
+
+// SecureValue is a secure value found in a .travis.yml file
+type SecureValue struct {
+	// The line of the file the value is on, starting from 1
+	Line int
+	// The base64 encoded ciphertext
+	Ciphertext string
+}
+
+// SecureValueStatus is the result of auditing a secure value
+type SecureValueStatus string
+
+const (
+	// SecureValueCurrent means the value was decrypted with the private key
+	// of the current key pair
+	SecureValueCurrent SecureValueStatus = "current"
+	// SecureValueStale means the value cannot have been encrypted for the
+	// current key pair, e.g. because it was encrypted before a key rotation
+	SecureValueStale SecureValueStatus = "stale"
+	// SecureValueUnverified means the value is consistent with the current
+	// key pair, but could not be decrypted to prove it as no private key was given
+	SecureValueUnverified SecureValueStatus = "unverified"
+)
+
+// SecureValueAudit is the result of auditing a secure value against a key pair
+type SecureValueAudit struct {
+	*SecureValue
+	// The status of the value
+	Status SecureValueStatus
+	// The decrypted value, only set when Status is SecureValueCurrent
+	Plaintext string
+	// The fingerprint of the key pair the value was audited against
+	Fingerprint string
+}
+
+var secureValuePattern = regexp.MustCompile(`(?:^|[\s{,-])secure:\s*["']?([A-Za-z0-9+/]+={0,2})["']?`)
+
+// FindSecureValues returns the secure values of a .travis.yml file
+// in the order they appear
+func FindSecureValues(yml []byte) []*SecureValue {
+	var values []*SecureValue
+
+	scanner := bufio.NewScanner(bytes.NewReader(yml))
+	scanner.Buffer(nil, len(yml)+1)
+	for line := 1; scanner.Scan(); line++ {
+		for _, m := range secureValuePattern.FindAllStringSubmatch(scanner.Text(), -1) {
+			values = append(values, &SecureValue{Line: line, Ciphertext: m[1]})
+		}
+	}
+
+	return values
+}
+
+// AuditSecureValues audits the secure values of a .travis.yml file
+// against the provided key pair.
+//
+// When privateKeyPEM is given, it must be the private key of the key pair and
+// each value is decrypted, so values which fail to decrypt are reported as stale.
+//
+// Without a private key, RSA only allows to prove that a value was not
+// encrypted for the public key of the key pair: values whose ciphertext has
+// the wrong size or is out of range for the key are reported as stale, and
+// the others as unverified. A value encrypted with an old key of the same
+// size is only detected as stale in a minority of cases, so unverified values
+// should not be taken as current.
+func AuditSecureValues(keyPair *KeyPair, yml []byte, privateKeyPEM string) ([]*SecureValueAudit, error) {
+	if keyPair == nil || keyPair.PublicKey == nil {
+		return nil, errors.New("travis: the key pair has no public key")
+	}
+
+	fingerprint, err := Fingerprint(*keyPair.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if keyPair.Fingerprint != nil && *keyPair.Fingerprint != fingerprint {
+		return nil, fmt.Errorf("travis: the public key does not match the fingerprint %s", *keyPair.Fingerprint)
+	}
+
+	publicKey, err := parsePublicKey(*keyPair.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var privateKey *rsa.PrivateKey
+	if privateKeyPEM != "" {
+		privateKey, err = parsePrivateKey(privateKeyPEM)
+		if err != nil {
+			return nil, err
+		}
+		if privateKey.N.Cmp(publicKey.N) != 0 || privateKey.E != publicKey.E {
+			return nil, fmt.Errorf("travis: the private key does not match the key pair %s", fingerprint)
+		}
+	}
+
+	var audits []*SecureValueAudit
+	for _, v := range FindSecureValues(yml) {
+		audit := &SecureValueAudit{SecureValue: v, Fingerprint: fingerprint}
+
+		switch {
+		case privateKey != nil:
+			audit.Status = SecureValueStale
+			if ciphertext, err := base64.StdEncoding.DecodeString(v.Ciphertext); err == nil {
+				if plaintext, err := rsa.DecryptPKCS1v15(nil, privateKey, ciphertext); err == nil {
+					audit.Status = SecureValueCurrent
+					audit.Plaintext = string(plaintext)
+				}
+			}
+		case ciphertextFitsKey(publicKey, v.Ciphertext):
+			audit.Status = SecureValueUnverified
+		default:
+			audit.Status = SecureValueStale
+		}
+
+		audits = append(audits, audit)
+	}
+
+	return audits, nil
+}
+
+// AuditSecureValuesByRepoId fetches the custom key pair of the repository with the provided id
+// and audits the secure values of a .travis.yml file against it, see AuditSecureValues.
+// Values encrypted by the travis CLI or Encrypt use the generated key pair unless a custom
+// one was uploaded, see GeneratedKeyPairService.AuditSecureValuesByRepoId
+//
+// Travis CI API docs: https://developer.travis-ci.com/resource/key_pair#find
+func (ks *KeyPairService) AuditSecureValuesByRepoId(ctx context.Context, repoId uint, yml []byte, privateKeyPEM string) ([]*SecureValueAudit, *http.Response, error) {
+	keyPair, resp, err := ks.FindByRepoId(ctx, repoId)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	audits, err := AuditSecureValues(keyPair, yml, privateKeyPEM)
+	return audits, resp, err
+}
+
+// AuditSecureValuesByRepoSlug fetches the custom key pair of the repository with the provided slug
+// and audits the secure values of a .travis.yml file against it, see AuditSecureValues.
+// Values encrypted by the travis CLI or Encrypt use the generated key pair unless a custom
+// one was uploaded, see GeneratedKeyPairService.AuditSecureValuesByRepoSlug
+//
+// Travis CI API docs: https://developer.travis-ci.com/resource/key_pair#find
+func (ks *KeyPairService) AuditSecureValuesByRepoSlug(ctx context.Context, repoSlug string, yml []byte, privateKeyPEM string) ([]*SecureValueAudit, *http.Response, error) {
+	keyPair, resp, err := ks.FindByRepoSlug(ctx, repoSlug)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	audits, err := AuditSecureValues(keyPair, yml, privateKeyPEM)
+	return audits, resp, err
+}
+
+// AuditSecureValuesByRepoId fetches the generated key pair of the repository with the provided id
+// and audits the secure values of a .travis.yml file against it, see AuditSecureValues.
+// This is the key pair the travis CLI and Encrypt encrypt values with.
+//
+// Travis CI API docs: https://developer.travis-ci.com/resource/key_pair_generated#find
+func (ks *GeneratedKeyPairService) AuditSecureValuesByRepoId(ctx context.Context, repoId uint, yml []byte, privateKeyPEM string) ([]*SecureValueAudit, *http.Response, error) {
+	keyPair, resp, err := ks.FindByRepoId(ctx, repoId)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	audits, err := AuditSecureValues(keyPair, yml, privateKeyPEM)
+	return audits, resp, err
+}
+
+// AuditSecureValuesByRepoSlug fetches the generated key pair of the repository with the provided slug
+// and audits the secure values of a .travis.yml file against it, see AuditSecureValues.
+// This is the key pair the travis CLI and Encrypt encrypt values with.
+//
+// Travis CI API docs: https://developer.travis-ci.com/resource/key_pair_generated#find
+func (ks *GeneratedKeyPairService) AuditSecureValuesByRepoSlug(ctx context.Context, repoSlug string, yml []byte, privateKeyPEM string) ([]*SecureValueAudit, *http.Response, error) {
+	keyPair, resp, err := ks.FindByRepoSlug(ctx, repoSlug)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	audits, err := AuditSecureValues(keyPair, yml, privateKeyPEM)
+	return audits, resp, err
+}
+
+// ciphertextFitsKey reports whether ciphertext may have been produced with
+// the provided key: a PKCS #1 v1.5 ciphertext is exactly as long as the
+// modulus and is smaller than it
+func ciphertextFitsKey(key *rsa.PublicKey, ciphertext string) bool {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return false
+	}
+
+	if len(data) != key.Size() {
+		return false
+	}
+
+	return new(big.Int).SetBytes(data).Cmp(key.N) < 0
+}
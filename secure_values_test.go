@@ -0,0 +1,180 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestFindSecureValues(t *testing.T) {
+	yml := []byte(`language: go
+env:
+  global:
+    - secure: "c2VjcmV0MQ=="
+    - secure: c2VjcmV0Mg==
+deploy:
+  api_key: {secure: 'c2VjcmV0Mw=='}
+# insecure: bm90IGEgc2VjdXJlIHZhbHVl
+`)
+
+	got := FindSecureValues(yml)
+	want := []*SecureValue{
+		{Line: 4, Ciphertext: "c2VjcmV0MQ=="},
+		{Line: 5, Ciphertext: "c2VjcmV0Mg=="},
+		{Line: 7, Ciphertext: "c2VjcmV0Mw=="},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindSecureValues returned %+v, want %+v", got, want)
+	}
+}
+
+func testSecureValuesYml(t *testing.T, key *rsa.PrivateKey) []byte {
+	current, err := Encrypt(testPublicKeyPEM(t, key, "PUBLIC KEY", false), "FOO=bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A value encrypted with an old key of a different size
+	oldKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := Encrypt(testPublicKeyPEM(t, oldKey, "PUBLIC KEY", false), "FOO=old")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A value of the right size which is out of range for the current key
+	outOfRange := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0xff}, key.Size()))
+
+	return []byte(fmt.Sprintf("env:\n  - secure: %q\n  - secure: %q\n  - secure: %q\n", current, old, outOfRange))
+}
+
+func TestAuditSecureValues(t *testing.T) {
+	key := testRSAKey(t)
+	yml := testSecureValuesYml(t, key)
+	keyPair := &KeyPair{PublicKey: String(testPublicKeyPEM(t, key, "RSA PUBLIC KEY", false))}
+	privateKey := string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+
+	cases := []struct {
+		privateKey string
+		want       []SecureValueStatus
+	}{
+		{privateKey, []SecureValueStatus{SecureValueCurrent, SecureValueStale, SecureValueStale}},
+		{"", []SecureValueStatus{SecureValueUnverified, SecureValueStale, SecureValueStale}},
+	}
+
+	for i, c := range cases {
+		audits, err := AuditSecureValues(keyPair, yml, c.privateKey)
+		if err != nil {
+			t.Fatalf("#%d AuditSecureValues returned error: %v", i, err)
+		}
+
+		var got []SecureValueStatus
+		for _, a := range audits {
+			got = append(got, a.Status)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("#%d AuditSecureValues returned %v, want %v", i, got, c.want)
+		}
+	}
+
+	audits, _ := AuditSecureValues(keyPair, yml, privateKey)
+	if audits[0].Plaintext != "FOO=bar" {
+		t.Errorf("AuditSecureValues decrypted %q, want %q", audits[0].Plaintext, "FOO=bar")
+	}
+}
+
+func TestAuditSecureValues_mismatchedKeys(t *testing.T) {
+	key := testRSAKey(t)
+	publicKey := testPublicKeyPEM(t, key, "PUBLIC KEY", false)
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPrivateKey := string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(otherKey)}))
+
+	cases := []struct {
+		keyPair    *KeyPair
+		privateKey string
+	}{
+		{&KeyPair{}, ""},
+		{&KeyPair{PublicKey: String(publicKey), Fingerprint: String(testKeyPairFingerprint)}, ""},
+		{&KeyPair{PublicKey: String(publicKey)}, otherPrivateKey},
+	}
+
+	for i, c := range cases {
+		if _, err := AuditSecureValues(c.keyPair, nil, c.privateKey); err == nil {
+			t.Errorf("#%d AuditSecureValues returned no error", i)
+		}
+	}
+}
+
+func TestKeyPairService_AuditSecureValuesByRepoSlug(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	key := testRSAKey(t)
+	publicKey := testPublicKeyPEM(t, key, "RSA PUBLIC KEY", false)
+	fingerprint, err := Fingerprint(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/key_pair", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		json.NewEncoder(w).Encode(map[string]string{"public_key": publicKey, "fingerprint": fingerprint})
+	})
+
+	audits, _, err := client.KeyPair.AuditSecureValuesByRepoSlug(context.Background(), testRepoSlug, testSecureValuesYml(t, key), "")
+
+	if err != nil {
+		t.Fatalf("KeyPair.AuditSecureValuesByRepoSlug returned error: %v", err)
+	}
+
+	if len(audits) != 3 || audits[0].Fingerprint != fingerprint || audits[1].Status != SecureValueStale {
+		t.Errorf("KeyPair.AuditSecureValuesByRepoSlug returned %+v", audits)
+	}
+}
+
+func TestGeneratedKeyPairService_AuditSecureValuesByRepoSlug(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	key := testRSAKey(t)
+	publicKey := testPublicKeyPEM(t, key, "RSA PUBLIC KEY", false)
+	fingerprint, err := Fingerprint(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/key_pair/generated", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		json.NewEncoder(w).Encode(map[string]string{"public_key": publicKey, "fingerprint": fingerprint})
+	})
+
+	audits, _, err := client.GeneratedKeyPair.AuditSecureValuesByRepoSlug(context.Background(), testRepoSlug, testSecureValuesYml(t, key), "")
+
+	if err != nil {
+		t.Fatalf("GeneratedKeyPair.AuditSecureValuesByRepoSlug returned error: %v", err)
+	}
+
+	if len(audits) != 3 || audits[0].Fingerprint != fingerprint || audits[1].Status != SecureValueStale {
+		t.Errorf("GeneratedKeyPair.AuditSecureValuesByRepoSlug returned %+v", audits)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"context"
+	"net/http"
+)
+
+// ConfigService handles communication with the config
+// endpoint of the Travis CI API.
+type ConfigService struct {
+	client *Client
+}
+
+// ServerConfig represents the configuration of a Travis CI server
+type ServerConfig struct {
+	// The host name of the server, e.g. travis-ci.com
+	Host *string `json:"host,omitempty"`
+	// The host name used for shortened URLs
+	ShortenHost *string `json:"shorten_host,omitempty"`
+	// The GitHub configuration of the server
+	GitHub *GitHubConfig `json:"github,omitempty"`
+	// The notifications configuration of the server
+	Notifications *NotificationsConfig `json:"notifications,omitempty"`
+}
+
+// GitHubConfig represents the GitHub configuration of a Travis CI server
+type GitHubConfig struct {
+	// The URL of the GitHub API
+	ApiUrl *string `json:"api_url,omitempty"`
+	// The OAuth scopes requested from GitHub
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// NotificationsConfig represents the notifications configuration of a Travis CI server
+type NotificationsConfig struct {
+	Webhook *WebhookConfig `json:"webhook,omitempty"`
+}
+
+// WebhookConfig represents the webhook notifications configuration of a Travis CI server
+type WebhookConfig struct {
+	// The PEM encoded public key verifying the signatures of webhook notifications
+	PublicKey *string `json:"public_key,omitempty"`
+}
+
+// serverConfigResponse represents the response of the config endpoint
+type serverConfigResponse struct {
+	Config *ServerConfig `json:"config"`
+}
+
+// Find fetches the configuration of the Travis CI server
+//
+// Travis CI docs: https://docs.travis-ci.com/user/notifications/#verifying-webhook-requests
+func (cs *ConfigService) Find(ctx context.Context) (*ServerConfig, *http.Response, error) {
+	req, err := cs.client.NewRequest(http.MethodGet, "config", nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The endpoint is not part of API V3, and is not served to V3 requests
+	req.Header.Del("Travis-API-Version")
+
+	var cr serverConfigResponse
+	resp, err := cs.client.Do(ctx, req, &cr)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return cr.Config, resp, err
+}
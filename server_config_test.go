@@ -0,0 +1,40 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestConfigService_Find(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/config", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		testHeader(t, r, "Travis-API-Version", "")
+		fmt.Fprint(w, `{"config":{"host":"travis-ci.com","github":{"api_url":"https://api.github.com","scopes":["read:org"]},"notifications":{"webhook":{"public_key":"key"}}}}`)
+	})
+
+	config, _, err := client.Config.Find(context.Background())
+
+	if err != nil {
+		t.Errorf("Config.Find returned error: %v", err)
+	}
+
+	want := &ServerConfig{
+		Host:          String("travis-ci.com"),
+		GitHub:        &GitHubConfig{ApiUrl: String("https://api.github.com"), Scopes: []string{"read:org"}},
+		Notifications: &NotificationsConfig{Webhook: &WebhookConfig{PublicKey: String("key")}},
+	}
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("Config.Find returned %+v, want %+v", config, want)
+	}
+}
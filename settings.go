@@ -25,7 +25,7 @@ type Setting struct {
 	// The setting's name
 	Name *string `json:"name,omitempty"`
 	// The setting's value
-	// Currently value can be boolean or integer
+	// Currently value can be boolean or integer, see BoolValue and IntValue
 	Value interface{} `json:"value,omitempty"`
 	*Metadata
 }
@@ -0,0 +1,209 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// sortDescSuffix is appended to an attribute to sort in descending order
+const sortDescSuffix = ":desc"
+
+const (
+	// BuildSortFieldId sorts builds by id
+	BuildSortFieldId = "id"
+	// BuildSortFieldStartedAt sorts builds by when they started
+	BuildSortFieldStartedAt = "started_at"
+	// BuildSortFieldFinishedAt sorts builds by when they finished
+	BuildSortFieldFinishedAt = "finished_at"
+)
+
+const (
+	// JobSortFieldId sorts jobs by id
+	JobSortFieldId = "id"
+)
+
+const (
+	// BranchSortFieldName sorts branches by name
+	BranchSortFieldName = "name"
+	// BranchSortFieldLastBuild sorts branches by their last build
+	BranchSortFieldLastBuild = "last_build"
+	// BranchSortFieldExistsOnGithub sorts branches by whether or not they still exist on GitHub
+	BranchSortFieldExistsOnGithub = "exists_on_github"
+	// BranchSortFieldDefaultBranch sorts branches by whether or not they are the default branch
+	BranchSortFieldDefaultBranch = "default_branch"
+)
+
+const (
+	// RepositorySortFieldId sorts repositories by id
+	RepositorySortFieldId = "id"
+	// RepositorySortFieldGitHubId sorts repositories by their id on GitHub
+	RepositorySortFieldGitHubId = "github_id"
+	// RepositorySortFieldOwnerName sorts repositories by the name of their owner
+	RepositorySortFieldOwnerName = "owner_name"
+	// RepositorySortFieldName sorts repositories by name
+	RepositorySortFieldName = "name"
+	// RepositorySortFieldActive sorts repositories by whether or not they are enabled on Travis CI
+	RepositorySortFieldActive = "active"
+	// RepositorySortFieldCurrentBuildFinishedAt sorts repositories by when their current build finished
+	RepositorySortFieldCurrentBuildFinishedAt = "current_build_finished_at"
+)
+
+const (
+	// OrganizationSortFieldId sorts organizations by id
+	OrganizationSortFieldId = "id"
+	// OrganizationSortFieldLogin sorts organizations by login
+	OrganizationSortFieldLogin = "login"
+	// OrganizationSortFieldName sorts organizations by name
+	OrganizationSortFieldName = "name"
+	// OrganizationSortFieldGitHubId sorts organizations by their id on GitHub
+	OrganizationSortFieldGitHubId = "github_id"
+)
+
+// sortableBy maps each resource type to the attributes its lists can be sorted by
+var sortableBy = map[string][]string{
+	"build": {
+		BuildSortFieldId,
+		BuildSortFieldStartedAt,
+		BuildSortFieldFinishedAt,
+	},
+	"job": {
+		JobSortFieldId,
+	},
+	"branch": {
+		BranchSortFieldName,
+		BranchSortFieldLastBuild,
+		BranchSortFieldExistsOnGithub,
+		BranchSortFieldDefaultBranch,
+	},
+	"repository": {
+		RepositorySortFieldId,
+		RepositorySortFieldGitHubId,
+		RepositorySortFieldOwnerName,
+		RepositorySortFieldName,
+		RepositorySortFieldActive,
+		RepositorySortFieldCurrentBuildFinishedAt,
+	},
+	"organization": {
+		OrganizationSortFieldId,
+		OrganizationSortFieldLogin,
+		OrganizationSortFieldName,
+		OrganizationSortFieldGitHubId,
+	},
+}
+
+// Sort specifies an attribute to sort a list by and the direction to sort it in
+type Sort struct {
+	// The attribute to sort by, e.g. BuildSortFieldId
+	Field string
+	// Whether or not to sort in descending order
+	Desc bool
+}
+
+// SortAsc returns a Sort sorting by the given attribute in ascending order
+func SortAsc(field string) Sort { return Sort{Field: field} }
+
+// SortDesc returns a Sort sorting by the given attribute in descending order
+func SortDesc(field string) Sort { return Sort{Field: field, Desc: true} }
+
+// String returns the sort in the form the Travis CI API expects,
+// e.g. `id` or `id:desc`
+func (s Sort) String() string {
+	if s.Desc {
+		return s.Field + sortDescSuffix
+	}
+
+	return s.Field
+}
+
+// SortOrder is a list of attributes to sort by, in order of precedence
+//
+// Travis CI API docs: https://developer.travis-ci.com/sorting
+type SortOrder []Sort
+
+// String returns the sort order in the form the Travis CI API expects,
+// e.g. `started_at:desc,id`
+func (so SortOrder) String() string {
+	s := make([]string, len(so))
+	for i, sort := range so {
+		s[i] = sort.String()
+	}
+
+	return strings.Join(s, ",")
+}
+
+// EncodeValues implements query.Encoder
+func (so SortOrder) EncodeValues(key string, v *url.Values) error {
+	if len(so) > 0 {
+		v.Set(key, so.String())
+	}
+
+	return nil
+}
+
+// InvalidSortError is returned when a list is requested
+// to be sorted by an attribute it cannot be sorted by
+type InvalidSortError struct {
+	// The resource type the endpoint returns
+	Resource string
+	// The offending attribute
+	Field string
+}
+
+func (e *InvalidSortError) Error() string {
+	return fmt.Sprintf("%s lists cannot be sorted by %q", e.Resource, e.Field)
+}
+
+// validateSortOrder checks that lists of the given
+// resource type can be sorted by every attribute in so
+func validateSortOrder(resource string, so SortOrder) error {
+	for _, s := range so {
+		valid := false
+		for _, f := range sortableBy[resource] {
+			if s.Field == f {
+				valid = true
+				break
+			}
+		}
+
+		if !valid {
+			return &InvalidSortError{Resource: resource, Field: s.Field}
+		}
+	}
+
+	return nil
+}
+
+// sorter is implemented by options which specify the order of a list
+type sorter interface {
+	sortOrder() (resource string, sortBy SortOrder)
+}
+
+func (opt *BuildsOption) sortOrder() (string, SortOrder) {
+	return "build", opt.SortBy
+}
+
+func (opt *BuildsByRepoOption) sortOrder() (string, SortOrder) {
+	return "build", opt.SortBy
+}
+
+func (opt *JobsOption) sortOrder() (string, SortOrder) {
+	return "job", opt.SortBy
+}
+
+func (opt *BranchesOption) sortOrder() (string, SortOrder) {
+	return "branch", opt.SortBy
+}
+
+func (opt *RepositoriesOption) sortOrder() (string, SortOrder) {
+	return "repository", opt.SortBy
+}
+
+func (opt *OrganizationsOption) sortOrder() (string, SortOrder) {
+	return "organization", opt.SortBy
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestSortOrder_String(t *testing.T) {
+	cases := []struct {
+		sortBy SortOrder
+		want   string
+	}{
+		{nil, ""},
+		{SortOrder{SortAsc(BuildSortFieldId)}, "id"},
+		{SortOrder{SortDesc(BuildSortFieldId)}, "id:desc"},
+		{SortOrder{SortDesc(BuildSortFieldStartedAt), SortAsc(BuildSortFieldId)}, "started_at:desc,id"},
+	}
+
+	for i, c := range cases {
+		if got := c.sortBy.String(); got != c.want {
+			t.Errorf("#%d SortOrder.String returned %q, want %q", i, got, c.want)
+		}
+	}
+}
+
+func TestSortOrder_encodesQueryString(t *testing.T) {
+	cases := []struct {
+		opt  interface{}
+		want string
+	}{
+		{&BuildsOption{}, "builds"},
+		{&BuildsOption{SortBy: SortOrder{SortDesc(BuildSortFieldFinishedAt), SortAsc(BuildSortFieldId)}}, "builds?sort_by=finished_at%3Adesc%2Cid"},
+		{&JobsOption{SortBy: SortOrder{SortDesc(JobSortFieldId)}}, "builds?sort_by=id%3Adesc"},
+		{&BranchesOption{SortBy: SortOrder{SortDesc(BranchSortFieldLastBuild)}}, "builds?sort_by=last_build%3Adesc"},
+		{&RepositoriesOption{SortBy: SortOrder{SortAsc(RepositorySortFieldCurrentBuildFinishedAt)}}, "builds?sort_by=current_build_finished_at"},
+		{&OrganizationsOption{SortBy: SortOrder{SortAsc(OrganizationSortFieldLogin)}}, "builds?sort_by=login"},
+	}
+
+	for i, c := range cases {
+		got, err := urlWithOptions("builds", c.opt)
+		if err != nil {
+			t.Fatalf("#%d urlWithOptions returned error: %v", i, err)
+		}
+		if got != c.want {
+			t.Errorf("#%d urlWithOptions returned %q, want %q", i, got, c.want)
+		}
+	}
+}
+
+func TestSortOrder_invalidFieldIsRejected(t *testing.T) {
+	cases := []struct {
+		opt  interface{}
+		want error
+	}{
+		{&BuildsByRepoOption{SortBy: SortOrder{SortAsc("name")}}, &InvalidSortError{Resource: "build", Field: "name"}},
+		{&JobsOption{SortBy: SortOrder{SortDesc(JobSortFieldId), SortAsc("started_at")}}, &InvalidSortError{Resource: "job", Field: "started_at"}},
+		{&BranchesOption{SortBy: SortOrder{SortAsc("id")}}, &InvalidSortError{Resource: "branch", Field: "id"}},
+	}
+
+	for i, c := range cases {
+		_, err := urlWithOptions("builds", c.opt)
+		if !reflect.DeepEqual(err, c.want) {
+			t.Errorf("#%d urlWithOptions returned error %v, want %v", i, err, c.want)
+		}
+	}
+}
+
+func TestBuildsService_ListByRepoSlug_sortDesc(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/builds", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		testFormValues(t, r, values{"sort_by": "started_at:desc,id:desc"})
+		fmt.Fprint(w, `{"builds": [{"id":1,"number":"1","state":"created","duration":10}]}`)
+	})
+
+	opt := BuildsByRepoOption{SortBy: SortOrder{SortDesc(BuildSortFieldStartedAt), SortDesc(BuildSortFieldId)}}
+	_, _, err := client.Builds.ListByRepoSlug(context.Background(), testRepoSlug, &opt)
+
+	if err != nil {
+		t.Errorf("Builds.ListByRepoSlug returned error: %v", err)
+	}
+}
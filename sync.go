@@ -0,0 +1,476 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Spec describes the desired configuration of a list of repositories,
+// see Client.Plan
+//
+// Specs are read from JSON with ParseSpec. YAML specs can be converted with
+// any YAML library honoring json struct tags.
+type Spec struct {
+	Repositories []*RepositorySpec `json:"repositories"`
+}
+
+// RepositorySpec describes the desired configuration of a repository.
+// Nil or empty fields are left unmanaged.
+type RepositorySpec struct {
+	// The slug of the repository, e.g. shuheiktgw/go-travis
+	Slug string `json:"slug"`
+	// The settings of the repository
+	Settings *RepositorySettings `json:"settings,omitempty"`
+	// The environment variables of the repository
+	EnvVars []*EnvVarSpec `json:"env_vars,omitempty"`
+	// The crons of the repository
+	Crons []*CronSpec `json:"crons,omitempty"`
+	// The key pair of the repository
+	KeyPair *KeyPairSpec `json:"key_pair,omitempty"`
+}
+
+// EnvVarSpec describes an environment variable.
+// Environment variables are identified by their name and branch.
+type EnvVarSpec struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Public bool   `json:"public,omitempty"`
+	Branch string `json:"branch,omitempty"`
+}
+
+// CronSpec describes a cron.
+// Crons are identified by their branch.
+type CronSpec struct {
+	Branch                     string `json:"branch"`
+	Interval                   string `json:"interval"`
+	DontRunIfRecentBuildExists bool   `json:"dont_run_if_recent_build_exists,omitempty"`
+}
+
+// KeyPairSpec describes a key pair.
+// A repository without a key pair gets a newly generated one.
+type KeyPairSpec struct {
+	Description string `json:"description"`
+}
+
+// ParseSpec parses a JSON encoded spec, rejecting unknown fields
+func ParseSpec(data []byte) (*Spec, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var spec Spec
+	if err := decoder.Decode(&spec); err != nil {
+		return nil, err
+	}
+
+	for i, rs := range spec.Repositories {
+		if rs == nil || rs.Slug == "" {
+			return nil, fmt.Errorf("travis: repository #%d of the spec has no slug", i)
+		}
+	}
+
+	return &spec, nil
+}
+
+// ChangeAction is the kind of a change
+type ChangeAction string
+
+const (
+	// ChangeCreate creates a resource
+	ChangeCreate ChangeAction = "+"
+	// ChangeUpdate updates a resource
+	ChangeUpdate ChangeAction = "~"
+	// ChangeDelete deletes a resource
+	ChangeDelete ChangeAction = "-"
+)
+
+// Change is a change to make to a repository to match a spec
+type Change struct {
+	// The slug of the repository
+	RepoSlug string
+	// The kind of the change
+	Action ChangeAction
+	// The kind of the changed resource: setting, env_var, cron or key_pair
+	Resource string
+	// The name of the changed resource
+	Name string
+	// A description of the change
+	Details string
+
+	apply func(ctx context.Context) (*http.Response, error)
+}
+
+func (c *Change) String() string {
+	s := fmt.Sprintf("%s %s %s", c.Action, c.Resource, c.Name)
+	if c.Details != "" {
+		s += " (" + c.Details + ")"
+	}
+	return s
+}
+
+// Plan is the list of changes to make to match a spec
+type Plan struct {
+	Changes []*Change
+}
+
+// String returns the changes of the plan grouped by repository,
+// which is the output of a dry run
+func (p *Plan) String() string {
+	if len(p.Changes) == 0 {
+		return "No changes.\n"
+	}
+
+	var b strings.Builder
+	repoSlug := ""
+	for _, c := range p.Changes {
+		if c.RepoSlug != repoSlug {
+			repoSlug = c.RepoSlug
+			fmt.Fprintf(&b, "%s:\n", repoSlug)
+		}
+		fmt.Fprintf(&b, "  %s\n", c)
+	}
+
+	return b.String()
+}
+
+// PlanOption specifies options for planning changes
+type PlanOption struct {
+	// Whether to delete environment variables and crons missing from the spec
+	Prune bool
+}
+
+// ApplyError is returned when a change of a plan fails to apply
+type ApplyError struct {
+	// The change which failed
+	Change *Change
+	// The error returned by the change
+	Err error
+}
+
+func (e *ApplyError) Error() string {
+	return fmt.Sprintf("travis: failed to apply %q on %s: %v", e.Change, e.Change.RepoSlug, e.Err)
+}
+
+// Unwrap returns the error returned by the change
+func (e *ApplyError) Unwrap() error {
+	return e.Err
+}
+
+// Plan compares the spec to the live configuration of its repositories and
+// returns the changes to make to match it.
+//
+// The values of private environment variables cannot be read back,
+// so private environment variables of the spec are always updated.
+func (c *Client) Plan(ctx context.Context, spec *Spec, opt *PlanOption) (*Plan, *http.Response, error) {
+	if opt == nil {
+		opt = &PlanOption{}
+	}
+
+	plan := &Plan{}
+	var resp *http.Response
+
+	for _, rs := range spec.Repositories {
+		var changes []*Change
+		var err error
+
+		for _, planner := range []func(context.Context, *RepositorySpec, *PlanOption) ([]*Change, *http.Response, error){
+			c.planSettings,
+			c.planEnvVars,
+			c.planCrons,
+			c.planKeyPair,
+		} {
+			changes, resp, err = planner(ctx, rs, opt)
+			if err != nil {
+				return nil, resp, err
+			}
+			plan.Changes = append(plan.Changes, changes...)
+		}
+	}
+
+	return plan, resp, nil
+}
+
+// Apply makes the changes of the plan in order,
+// and stops at the first failing change with an ApplyError
+func (c *Client) Apply(ctx context.Context, plan *Plan) (*http.Response, error) {
+	var resp *http.Response
+
+	for _, change := range plan.Changes {
+		var err error
+		resp, err = change.apply(ctx)
+		if err != nil {
+			return resp, &ApplyError{Change: change, Err: err}
+		}
+	}
+
+	return resp, nil
+}
+
+func (c *Client) planSettings(ctx context.Context, rs *RepositorySpec, opt *PlanOption) ([]*Change, *http.Response, error) {
+	if rs.Settings == nil {
+		return nil, nil, nil
+	}
+
+	current, resp, err := c.Settings.GetAllByRepoSlug(ctx, rs.Slug)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	var changes []*Change
+	for _, setting := range current.Changes(rs.Settings) {
+		setting := setting
+		changes = append(changes, &Change{
+			RepoSlug: rs.Slug,
+			Action:   ChangeUpdate,
+			Resource: "setting",
+			Name:     setting.Name,
+			Details:  fmt.Sprintf("%v", setting.Value),
+			apply: func(ctx context.Context) (*http.Response, error) {
+				_, resp, err := c.Settings.UpdateByRepoSlug(ctx, rs.Slug, setting)
+				return resp, err
+			},
+		})
+	}
+
+	return changes, resp, nil
+}
+
+func (c *Client) planEnvVars(ctx context.Context, rs *RepositorySpec, opt *PlanOption) ([]*Change, *http.Response, error) {
+	if len(rs.EnvVars) == 0 && !opt.Prune {
+		return nil, nil, nil
+	}
+
+	live, resp, err := c.EnvVars.ListByRepoSlug(ctx, rs.Slug)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	envVarKey := func(name, branch string) string {
+		return name + "\x00" + branch
+	}
+
+	liveByKey := map[string]*EnvVar{}
+	for _, ev := range live {
+		if ev.Name == nil || ev.Id == nil {
+			continue
+		}
+		branch := ""
+		if ev.Branch != nil {
+			branch = *ev.Branch
+		}
+		liveByKey[envVarKey(*ev.Name, branch)] = ev
+	}
+
+	var changes []*Change
+	for _, spec := range rs.EnvVars {
+		spec := spec
+		key := envVarKey(spec.Name, spec.Branch)
+		body := &EnvVarBody{Name: spec.Name, Value: spec.Value, Public: spec.Public, Branch: spec.Branch}
+		name := envVarDisplayName(spec.Name, spec.Branch)
+
+		ev, ok := liveByKey[key]
+		delete(liveByKey, key)
+
+		switch {
+		case !ok:
+			changes = append(changes, &Change{
+				RepoSlug: rs.Slug,
+				Action:   ChangeCreate,
+				Resource: "env_var",
+				Name:     name,
+				Details:  envVarVisibility(spec.Public),
+				apply: func(ctx context.Context) (*http.Response, error) {
+					_, resp, err := c.EnvVars.CreateByRepoSlug(ctx, rs.Slug, body)
+					return resp, err
+				},
+			})
+		case !spec.Public || ev.Public == nil || !*ev.Public || ev.Value == nil || *ev.Value != spec.Value:
+			id := *ev.Id
+			changes = append(changes, &Change{
+				RepoSlug: rs.Slug,
+				Action:   ChangeUpdate,
+				Resource: "env_var",
+				Name:     name,
+				Details:  envVarVisibility(spec.Public),
+				apply: func(ctx context.Context) (*http.Response, error) {
+					_, resp, err := c.EnvVars.UpdateByRepoSlug(ctx, rs.Slug, id, body)
+					return resp, err
+				},
+			})
+		}
+	}
+
+	if opt.Prune {
+		// Iterate over the live environment variables to keep their order
+		for _, ev := range live {
+			if ev.Name == nil || ev.Id == nil {
+				continue
+			}
+			branch := ""
+			if ev.Branch != nil {
+				branch = *ev.Branch
+			}
+			if _, ok := liveByKey[envVarKey(*ev.Name, branch)]; !ok {
+				continue
+			}
+
+			id := *ev.Id
+			changes = append(changes, &Change{
+				RepoSlug: rs.Slug,
+				Action:   ChangeDelete,
+				Resource: "env_var",
+				Name:     envVarDisplayName(*ev.Name, branch),
+				apply: func(ctx context.Context) (*http.Response, error) {
+					return c.EnvVars.DeleteByRepoSlug(ctx, rs.Slug, id)
+				},
+			})
+		}
+	}
+
+	return changes, resp, nil
+}
+
+func envVarVisibility(public bool) string {
+	if public {
+		return "public"
+	}
+	return "private"
+}
+
+func (c *Client) planCrons(ctx context.Context, rs *RepositorySpec, opt *PlanOption) ([]*Change, *http.Response, error) {
+	if len(rs.Crons) == 0 && !opt.Prune {
+		return nil, nil, nil
+	}
+
+	live, resp, err := c.Crons.listAllByRepoSlug(ctx, rs.Slug)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	liveByBranch := map[string]*Cron{}
+	for _, cron := range live {
+		if cron.Id != nil && cron.Branch != nil && cron.Branch.Name != nil {
+			liveByBranch[*cron.Branch.Name] = cron
+		}
+	}
+
+	var changes []*Change
+	for _, spec := range rs.Crons {
+		spec := spec
+		body := &CronBody{Interval: spec.Interval, DontRunIfRecentBuildExists: spec.DontRunIfRecentBuildExists}
+		details := spec.Interval
+		if spec.DontRunIfRecentBuildExists {
+			details += ", unless a recent build exists"
+		}
+
+		cron, ok := liveByBranch[spec.Branch]
+		delete(liveByBranch, spec.Branch)
+
+		switch {
+		case !ok:
+			changes = append(changes, &Change{
+				RepoSlug: rs.Slug,
+				Action:   ChangeCreate,
+				Resource: "cron",
+				Name:     spec.Branch,
+				Details:  details,
+				apply: func(ctx context.Context) (*http.Response, error) {
+					_, resp, err := c.Crons.CreateByRepoSlug(ctx, rs.Slug, spec.Branch, body)
+					return resp, err
+				},
+			})
+		case cron.Interval == nil || *cron.Interval != spec.Interval ||
+			cron.DontRunIfRecentBuildExists == nil || *cron.DontRunIfRecentBuildExists != spec.DontRunIfRecentBuildExists:
+			// Crons cannot be updated, so they are replaced,
+			// creating the new one before deleting the existing one
+			id := *cron.Id
+			changes = append(changes, &Change{
+				RepoSlug: rs.Slug,
+				Action:   ChangeUpdate,
+				Resource: "cron",
+				Name:     spec.Branch,
+				Details:  details,
+				apply: func(ctx context.Context) (*http.Response, error) {
+					_, resp, err := c.Crons.replaceByRepoSlug(ctx, rs.Slug, spec.Branch, id, body)
+					return resp, err
+				},
+			})
+		}
+	}
+
+	if opt.Prune {
+		for _, cron := range live {
+			if cron.Id == nil || cron.Branch == nil || cron.Branch.Name == nil {
+				continue
+			}
+			if _, ok := liveByBranch[*cron.Branch.Name]; !ok {
+				continue
+			}
+
+			id := *cron.Id
+			changes = append(changes, &Change{
+				RepoSlug: rs.Slug,
+				Action:   ChangeDelete,
+				Resource: "cron",
+				Name:     *cron.Branch.Name,
+				apply: func(ctx context.Context) (*http.Response, error) {
+					return c.Crons.Delete(ctx, id)
+				},
+			})
+		}
+	}
+
+	return changes, resp, nil
+}
+
+func (c *Client) planKeyPair(ctx context.Context, rs *RepositorySpec, opt *PlanOption) ([]*Change, *http.Response, error) {
+	if rs.KeyPair == nil {
+		return nil, nil, nil
+	}
+
+	keyPair, resp, err := c.KeyPair.FindByRepoSlug(ctx, rs.Slug)
+	switch {
+	case err == nil:
+		if keyPair.Description != nil && *keyPair.Description == rs.KeyPair.Description {
+			return nil, resp, nil
+		}
+
+		body := &KeyPairBody{Description: rs.KeyPair.Description}
+		return []*Change{{
+			RepoSlug: rs.Slug,
+			Action:   ChangeUpdate,
+			Resource: "key_pair",
+			Name:     rs.KeyPair.Description,
+			apply: func(ctx context.Context) (*http.Response, error) {
+				_, resp, err := c.KeyPair.UpdateByRepoSlug(ctx, rs.Slug, body)
+				return resp, err
+			},
+		}}, resp, nil
+	case resp != nil && resp.StatusCode == http.StatusNotFound:
+		return []*Change{{
+			RepoSlug: rs.Slug,
+			Action:   ChangeCreate,
+			Resource: "key_pair",
+			Name:     rs.KeyPair.Description,
+			Details:  "generated",
+			apply: func(ctx context.Context) (*http.Response, error) {
+				body, err := GenerateKeyPairBody(rs.KeyPair.Description, 0)
+				if err != nil {
+					return nil, err
+				}
+				_, resp, err := c.KeyPair.RotateByRepoSlug(ctx, rs.Slug, body)
+				return resp, err
+			},
+		}}, resp, nil
+	default:
+		return nil, resp, err
+	}
+}
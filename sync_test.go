@@ -0,0 +1,222 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+const testSpec = `{
+  "repositories": [{
+    "slug": "shuheiktgw/go-travis-test",
+    "settings": {"auto_cancel_pushes": true, "build_pushes": true},
+    "env_vars": [
+      {"name": "PUBLIC", "value": "unchanged", "public": true},
+      {"name": "CHANGED", "value": "new", "public": true},
+      {"name": "SECRET", "value": "secret"},
+      {"name": "NEW", "value": "new", "branch": "develop"}
+    ],
+    "crons": [
+      {"branch": "master", "interval": "daily"},
+      {"branch": "develop", "interval": "weekly", "dont_run_if_recent_build_exists": true}
+    ],
+    "key_pair": {"description": "deploy key"}
+  }]
+}`
+
+// testSyncMux registers handlers serving the live configuration of testRepoSlug
+// and returns the list of modifying requests received
+func testSyncMux(t *testing.T, mux *http.ServeMux) *[]string {
+	var requests []string
+	record := func(r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+	}
+
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/settings", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"settings":[{"name":"auto_cancel_pushes","value":false},{"name":"build_pushes","value":true}]}`)
+	})
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/setting/auto_cancel_pushes", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		testBody(t, r, `{"setting.value":true}`+"\n")
+		fmt.Fprint(w, `{"name":"auto_cancel_pushes","value":true}`)
+	})
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/env_vars", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			record(r)
+			testBody(t, r, `{"env_var.name":"NEW","env_var.value":"new","env_var.public":false,"env_var.branch":"develop"}`+"\n")
+			fmt.Fprint(w, `{"id":"4","name":"NEW"}`)
+			return
+		}
+		fmt.Fprint(w, `{"env_vars":[
+		  {"id":"1","name":"PUBLIC","value":"unchanged","public":true},
+		  {"id":"2","name":"CHANGED","value":"old","public":true},
+		  {"id":"3","name":"SECRET","public":false},
+		  {"id":"5","name":"UNMANAGED","public":false}
+		]}`)
+	})
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/env_var/", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		fmt.Fprint(w, `{"id":"1"}`)
+	})
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/crons", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		testFormValues(t, r, values{"limit": "100"})
+		fmt.Fprint(w, `{"crons":[
+		  {"id":1,"branch":{"name":"master"},"interval":"daily","dont_run_if_recent_build_exists":false},
+		  {"id":2,"branch":{"name":"develop"},"interval":"daily","dont_run_if_recent_build_exists":false},
+		  {"id":3,"branch":{"name":"feature"},"interval":"monthly","dont_run_if_recent_build_exists":false}
+		]}`)
+	})
+	mux.HandleFunc("/cron/", func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/branch/develop/cron", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		testBody(t, r, `{"cron.interval":"weekly","cron.dont_run_if_recent_build_exists":true}`+"\n")
+		fmt.Fprint(w, `{"id":4}`)
+	})
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/key_pair", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPatch {
+			record(r)
+			testBody(t, r, `{"key_pair.description":"deploy key"}`+"\n")
+		}
+		fmt.Fprint(w, `{"description":"old key"}`)
+	})
+
+	return &requests
+}
+
+func TestParseSpec(t *testing.T) {
+	spec, err := ParseSpec([]byte(testSpec))
+	if err != nil {
+		t.Fatalf("ParseSpec returned error: %v", err)
+	}
+
+	rs := spec.Repositories[0]
+	if rs.Slug != testRepoSlug || !*rs.Settings.AutoCancelPushes || len(rs.EnvVars) != 4 || len(rs.Crons) != 2 || rs.KeyPair.Description != "deploy key" {
+		t.Errorf("ParseSpec returned %+v", rs)
+	}
+
+	for i, s := range []string{
+		`{"repositories":[{"slug":"a/b","unknown":true}]}`,
+		`{"repositories":[{"settings":{}}]}`,
+		`{"repositories":[{"slug":"a/b","settings":{"build_pushes":"yes"}}]}`,
+	} {
+		if _, err := ParseSpec([]byte(s)); err == nil {
+			t.Errorf("#%d ParseSpec returned no error", i)
+		}
+	}
+}
+
+func TestClient_Plan(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	requests := testSyncMux(t, mux)
+
+	spec, err := ParseSpec([]byte(testSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan, _, err := client.Plan(context.Background(), spec, &PlanOption{Prune: true})
+
+	if err != nil {
+		t.Fatalf("Client.Plan returned error: %v", err)
+	}
+
+	want := `shuheiktgw/go-travis-test:
+  ~ setting auto_cancel_pushes (true)
+  ~ env_var CHANGED (public)
+  ~ env_var SECRET (private)
+  + env_var NEW@develop (private)
+  - env_var UNMANAGED
+  ~ cron develop (weekly, unless a recent build exists)
+  - cron feature
+  ~ key_pair deploy key
+`
+	if got := plan.String(); got != want {
+		t.Errorf("Client.Plan returned\n%s\nwant\n%s", got, want)
+	}
+
+	if len(*requests) != 0 {
+		t.Errorf("Client.Plan made modifying requests %v", *requests)
+	}
+}
+
+func TestClient_Apply(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	requests := testSyncMux(t, mux)
+
+	spec, err := ParseSpec([]byte(testSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan, _, err := client.Plan(context.Background(), spec, nil)
+	if err != nil {
+		t.Fatalf("Client.Plan returned error: %v", err)
+	}
+
+	if _, err := client.Apply(context.Background(), plan); err != nil {
+		t.Fatalf("Client.Apply returned error: %v", err)
+	}
+
+	want := []string{
+		"PATCH /repo/shuheiktgw/go-travis-test/setting/auto_cancel_pushes",
+		"PATCH /repo/shuheiktgw/go-travis-test/env_var/2",
+		"PATCH /repo/shuheiktgw/go-travis-test/env_var/3",
+		"POST /repo/shuheiktgw/go-travis-test/env_vars",
+		"POST /repo/shuheiktgw/go-travis-test/branch/develop/cron",
+		"DELETE /cron/2",
+		"PATCH /repo/shuheiktgw/go-travis-test/key_pair",
+	}
+	if !reflect.DeepEqual(*requests, want) {
+		t.Errorf("Client.Apply made requests %v, want %v", *requests, want)
+	}
+}
+
+func TestClient_Apply_stopsAtFirstError(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/settings", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"settings":[]}`)
+	})
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/setting/", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"error_type":"insufficient_access","error_message":"forbidden"}`)
+	})
+
+	spec := &Spec{Repositories: []*RepositorySpec{{
+		Slug:     testRepoSlug,
+		Settings: &RepositorySettings{BuildPushes: Bool(true), AutoCancelPushes: Bool(true)},
+	}}}
+
+	plan, _, err := client.Plan(context.Background(), spec, nil)
+	if err != nil {
+		t.Fatalf("Client.Plan returned error: %v", err)
+	}
+
+	_, err = client.Apply(context.Background(), plan)
+
+	var applyErr *ApplyError
+	if !errors.As(err, &applyErr) || applyErr.Change != plan.Changes[0] {
+		t.Fatalf("Client.Apply returned error %v, want an ApplyError for the first change", err)
+	}
+
+	var errorResponse *ErrorResponse
+	if !errors.As(err, &errorResponse) || errorResponse.ErrorType != "insufficient_access" {
+		t.Errorf("Client.Apply returned error %v, want insufficient_access", err)
+	}
+}
@@ -16,6 +16,8 @@ import (
 	"net/url"
 	"reflect"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/go-querystring/query"
 )
@@ -58,6 +60,7 @@ type Client struct {
 	Broadcasts            *BroadcastsService
 	Builds                *BuildsService
 	Caches                *CachesService
+	Config                *ConfigService
 	Crons                 *CronsService
 	EmailSubscriptions    *EmailSubscriptionsService
 	EnvVars               *EnvVarsService
@@ -76,6 +79,11 @@ type Client struct {
 	Settings              *SettingsService
 	Stages                *StagesService
 	User                  *UserService
+
+	// Description of the API set by Discover, used
+	// to reject requests to unsupported endpoints
+	homeMu sync.RWMutex
+	home   *Home
 }
 
 // NewClient returns a new Travis API client.
@@ -104,6 +112,7 @@ func NewClient(baseUrl string, travisToken string) *Client {
 	c.Broadcasts = &BroadcastsService{client: c}
 	c.Builds = &BuildsService{client: c}
 	c.Caches = &CachesService{client: c}
+	c.Config = &ConfigService{client: c}
 	c.Crons = &CronsService{client: c}
 	c.EmailSubscriptions = &EmailSubscriptionsService{client: c}
 	c.EnvVars = &EnvVarsService{client: c}
@@ -153,6 +162,11 @@ func (c *Client) NewRequest(method, urlStr string, body interface{}, headers map
 		return nil, err
 	}
 
+	path := "/" + strings.TrimPrefix(u.EscapedPath(), c.BaseURL.EscapedPath())
+	if err := c.checkSupported(method, path); err != nil {
+		return nil, err
+	}
+
 	var buf io.ReadWriter
 	if body != nil {
 		buf = new(bytes.Buffer)
@@ -167,14 +181,12 @@ func (c *Client) NewRequest(method, urlStr string, body interface{}, headers map
 		return nil, err
 	}
 
-	var h = c.Headers
-	if headers != nil {
-		for k, v := range headers {
-			h[k] = v
-		}
+	for k, v := range c.Headers {
+		req.Header.Set(k, v)
 	}
 
-	for k, v := range h {
+	// Request specific headers must not leak into the client headers
+	for k, v := range headers {
 		req.Header.Set(k, v)
 	}
 
@@ -215,7 +227,7 @@ func (c *Client) Do(ctx context.Context, req *http.Request, v interface{}) (*htt
 
 	if v != nil {
 		if w, ok := v.(io.Writer); ok {
-			io.Copy(w, resp.Body)
+			_, err = io.Copy(w, resp.Body)
 		} else {
 			err = json.NewDecoder(resp.Body).Decode(v)
 		}
@@ -300,6 +312,18 @@ func urlWithOptions(s string, opt interface{}) (string, error) {
 		return s, nil
 	}
 
+	if i, ok := opt.(includer); ok {
+		if err := validateIncludes(i.includes()); err != nil {
+			return s, err
+		}
+	}
+
+	if so, ok := opt.(sorter); ok {
+		if err := validateSortOrder(so.sortOrder()); err != nil {
+			return s, err
+		}
+	}
+
 	u, err := url.Parse(s)
 	if err != nil {
 		return s, err
@@ -318,6 +342,17 @@ func withContext(ctx context.Context, req *http.Request) *http.Request {
 	return req.WithContext(ctx)
 }
 
+// parseTime parses a timestamp returned by the API, formatted in RFC 3339.
+// It returns false if the timestamp is missing or malformed.
+func parseTime(s *string) (time.Time, bool) {
+	if s == nil {
+		return time.Time{}, false
+	}
+
+	t, err := time.Parse(time.RFC3339, *s)
+	return t, err == nil
+}
+
 // Permissions represents permissions of Travis CI API
 type Permissions map[string]bool
 
@@ -329,6 +364,10 @@ func Bool(v bool) *bool { return &v }
 // to store v and returns a pointer to it.
 func Uint(v uint) *uint { return &v }
 
+// Int is a helper routine that allocates a new int value
+// to store v and returns a pointer to it.
+func Int(v int) *int { return &v }
+
 // Int64 is a helper routine that allocates a new Int64 value
 // to store v and returns a pointer to it.
 func Int64(v int64) *int64 { return &v }
@@ -0,0 +1,404 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// WatchEventType is the type of an event emitted by a Watcher
+type WatchEventType string
+
+// Types of events emitted by a Watcher
+const (
+	// WatchEventBuildCreated is emitted when a build is created or restarted
+	WatchEventBuildCreated WatchEventType = "build_created"
+	// WatchEventBuildStarted is emitted when a build starts
+	WatchEventBuildStarted WatchEventType = "build_started"
+	// WatchEventBuildFinished is emitted when a build passes, fails, errors or is canceled
+	WatchEventBuildFinished WatchEventType = "build_finished"
+	// WatchEventJobStateChanged is emitted when the state of a job changes
+	WatchEventJobStateChanged WatchEventType = "job_state_changed"
+	// WatchEventStageFinished is emitted when all the jobs of a stage finished
+	WatchEventStageFinished WatchEventType = "stage_finished"
+)
+
+// WatchEvent is an event emitted by a Watcher
+type WatchEvent struct {
+	Type WatchEventType
+	// The slug of the repository of the build
+	RepoSlug string
+	// The build, set for all the events
+	Build *Build
+	// The job, set for WatchEventJobStateChanged
+	Job *Job
+	// The stage, set for WatchEventStageFinished
+	Stage *Stage
+	// The state of the build, job or stage before the event,
+	// empty when it was not known yet
+	PreviousState string
+}
+
+// WatcherOption specifies the options of a Watcher
+type WatcherOption struct {
+	// Repositories whose recent builds are watched
+	RepoSlugs []string
+	// Owner whose active builds are watched
+	Owner string
+	// How many recent builds of each repository are watched. Defaults to 25.
+	BuildsLimit int
+	// Interval between polls while builds are running. Defaults to 10 seconds.
+	MinInterval time.Duration
+	// Interval between polls the interval backs off to while nothing happens.
+	// Defaults to 2 minutes.
+	MaxInterval time.Duration
+	// OnError is called when a poll fails, and the watcher retries after
+	// MaxInterval. When nil, Run returns the error instead.
+	OnError func(error)
+}
+
+// WatchState is the state of a Watcher, the last known states of the
+// builds, jobs and stages it watches. It can be encoded with encoding/json
+// and restored to resume watching without missing events.
+type WatchState struct {
+	Builds map[uint]string `json:"builds"`
+	Jobs   map[uint]string `json:"jobs"`
+	Stages map[uint]string `json:"stages"`
+}
+
+// Watcher polls the builds of repositories, or the active builds of an owner,
+// and emits events when they change.
+//
+// The first poll only records the current state, unless a state was restored.
+// The watcher polls every MinInterval while builds are running,
+// and backs off up to MaxInterval while nothing happens.
+type Watcher struct {
+	client *Client
+	opt    WatcherOption
+
+	mu    sync.Mutex
+	state *WatchState
+}
+
+// NewWatcher returns a Watcher polling with client
+func NewWatcher(client *Client, opt *WatcherOption) *Watcher {
+	w := &Watcher{client: client}
+	if opt != nil {
+		w.opt = *opt
+	}
+	if w.opt.BuildsLimit == 0 {
+		w.opt.BuildsLimit = 25
+	}
+	if w.opt.MinInterval == 0 {
+		w.opt.MinInterval = 10 * time.Second
+	}
+	if w.opt.MaxInterval == 0 {
+		w.opt.MaxInterval = 2 * time.Minute
+	}
+	return w
+}
+
+// State returns a copy of the state of the watcher
+func (w *Watcher) State() *WatchState {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.state == nil {
+		return nil
+	}
+	return w.state.copy()
+}
+
+// Restore restores a state returned by State, so the next poll
+// emits the events which happened since it was saved
+func (w *Watcher) Restore(state *WatchState) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if state == nil {
+		w.state = nil
+		return
+	}
+	w.state = state.copy()
+}
+
+func (s *WatchState) copy() *WatchState {
+	c := &WatchState{Builds: map[uint]string{}, Jobs: map[uint]string{}, Stages: map[uint]string{}}
+	for k, v := range s.Builds {
+		c.Builds[k] = v
+	}
+	for k, v := range s.Jobs {
+		c.Jobs[k] = v
+	}
+	for k, v := range s.Stages {
+		c.Stages[k] = v
+	}
+	return c
+}
+
+// Run polls until ctx is done and sends the events to events.
+// The state of the watcher only advances once all the events of a poll
+// were sent, so when ctx is done while sending them, the events of the
+// poll are emitted again after restarting from State.
+func (w *Watcher) Run(ctx context.Context, events chan<- *WatchEvent) error {
+	interval := w.opt.MinInterval
+
+	for {
+		polled, next, running, _, err := w.poll(ctx)
+		switch {
+		case err != nil && w.opt.OnError == nil:
+			return err
+		case err != nil:
+			w.opt.OnError(err)
+			interval = w.opt.MaxInterval
+		case len(polled) > 0 || running:
+			interval = w.opt.MinInterval
+		default:
+			interval *= 2
+			if interval > w.opt.MaxInterval {
+				interval = w.opt.MaxInterval
+			}
+		}
+
+		for _, e := range polled {
+			select {
+			case events <- e:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if next != nil {
+			w.advance(next)
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Poll polls once and returns the events since the previous poll
+func (w *Watcher) Poll(ctx context.Context) ([]*WatchEvent, *http.Response, error) {
+	events, next, _, resp, err := w.poll(ctx)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	w.advance(next)
+
+	return events, resp, nil
+}
+
+// advance replaces the state of the watcher once the events leading to it were emitted
+func (w *Watcher) advance(next *WatchState) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.state = next
+}
+
+// poll polls once, and returns the events since the previous poll,
+// the state to advance to once they are emitted, and if builds are running
+func (w *Watcher) poll(ctx context.Context) ([]*WatchEvent, *WatchState, bool, *http.Response, error) {
+	builds, resp, err := w.fetch(ctx)
+	if err != nil {
+		return nil, nil, false, resp, err
+	}
+
+	// The state is replaced rather than modified, so a snapshot of it can be
+	// read without holding the lock while the missing builds are fetched
+	w.mu.Lock()
+	prev := w.state
+	w.mu.Unlock()
+
+	// Builds which stopped being listed while running, e.g. because
+	// active builds are not listed anymore once they finished,
+	// are fetched to learn how they finished
+	if prev != nil {
+		listed := map[uint]bool{}
+		for _, b := range builds {
+			listed[*b.Id] = true
+		}
+		var missing []uint
+		for id, state := range prev.Builds {
+			if !listed[id] && !buildStateFinished(state) {
+				missing = append(missing, id)
+			}
+		}
+		sort.Slice(missing, func(i, j int) bool { return missing[i] < missing[j] })
+
+		for _, id := range missing {
+			opt := &BuildOption{Include: []string{BuildInclude.Jobs, BuildInclude.Stages}}
+			b, resp, err := w.client.Builds.Find(ctx, id, opt)
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				continue
+			}
+			if err != nil {
+				return nil, nil, false, resp, err
+			}
+			if b.Id != nil {
+				builds = append(builds, b)
+			}
+		}
+	}
+
+	next := &WatchState{Builds: map[uint]string{}, Jobs: map[uint]string{}, Stages: map[uint]string{}}
+	var events []*WatchEvent
+	running := false
+
+	for _, b := range builds {
+		if buildRunning(b) {
+			running = true
+		}
+		events = append(events, diffBuild(prev, b, next)...)
+	}
+
+	// The first poll only records the current state
+	if prev == nil {
+		events = nil
+	}
+
+	return events, next, running, resp, nil
+}
+
+// fetch lists the watched builds, with their jobs and stages
+func (w *Watcher) fetch(ctx context.Context) ([]*Build, *http.Response, error) {
+	var builds []*Build
+	var resp *http.Response
+	var err error
+
+	if w.opt.Owner != "" {
+		opt := &ActiveOption{Include: []string{BuildInclude.Jobs, BuildInclude.Stages}}
+		builds, resp, err = w.client.Active.FindByOwner(ctx, w.opt.Owner, opt)
+		if err != nil {
+			return nil, resp, err
+		}
+	}
+
+	for _, slug := range w.opt.RepoSlugs {
+		opt := &BuildsByRepoOption{
+			Limit:   w.opt.BuildsLimit,
+			Include: []string{BuildInclude.Jobs, BuildInclude.Stages},
+		}
+		var repoBuilds []*Build
+		repoBuilds, resp, err = w.client.Builds.ListByRepoSlug(ctx, slug, opt)
+		if err != nil {
+			return nil, resp, err
+		}
+		for _, b := range repoBuilds {
+			if b.Repository == nil {
+				b.Repository = &Repository{Slug: String(slug)}
+			}
+		}
+		builds = append(builds, repoBuilds...)
+	}
+
+	// A build may be both active and in a watched repository
+	seen := map[uint]bool{}
+	unique := builds[:0]
+	for _, b := range builds {
+		if b.Id == nil || seen[*b.Id] {
+			continue
+		}
+		seen[*b.Id] = true
+		unique = append(unique, b)
+	}
+
+	// Oldest first, so events are emitted in the order they likely happened
+	sort.SliceStable(unique, func(i, j int) bool { return *unique[i].Id < *unique[j].Id })
+
+	return unique, resp, nil
+}
+
+// buildPhase orders the states of builds: pending, running, finished
+func buildPhase(state string) int {
+	switch {
+	case buildStateFinished(state):
+		return 2
+	case state == BuildStateStarted:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// diffBuild returns the events of a build since the previous poll,
+// whose state is prev, and records its state in next
+func diffBuild(prev *WatchState, b *Build, next *WatchState) []*WatchEvent {
+	if prev == nil {
+		prev = &WatchState{}
+	}
+
+	slug := ""
+	if b.Repository != nil && b.Repository.Slug != nil {
+		slug = *b.Repository.Slug
+	}
+	event := func(typ WatchEventType, previous string) *WatchEvent {
+		return &WatchEvent{Type: typ, RepoSlug: slug, Build: b, PreviousState: previous}
+	}
+
+	var events []*WatchEvent
+
+	state := ""
+	if b.State != nil {
+		state = *b.State
+	}
+	next.Builds[*b.Id] = state
+
+	prevState, known := prev.Builds[*b.Id]
+	if prevState != state || !known {
+		prevPhase, phase := buildPhase(prevState), buildPhase(state)
+		if !known || phase < prevPhase {
+			// New or restarted
+			events = append(events, event(WatchEventBuildCreated, prevState))
+			prevPhase = 0
+		}
+		if prevPhase < 1 && phase >= 1 && (phase == 1 || b.StartedAt != nil) {
+			events = append(events, event(WatchEventBuildStarted, prevState))
+		}
+		if prevPhase < 2 && phase == 2 {
+			events = append(events, event(WatchEventBuildFinished, prevState))
+		}
+	}
+
+	for _, j := range b.Jobs {
+		if j.Id == nil || j.State == nil {
+			continue
+		}
+		next.Jobs[*j.Id] = *j.State
+
+		prevState, known := prev.Jobs[*j.Id]
+		if prevState == *j.State || (!known && *j.State == BuildStateCreated) {
+			continue
+		}
+		e := event(WatchEventJobStateChanged, prevState)
+		e.Job = j
+		events = append(events, e)
+	}
+
+	for _, s := range b.Stages {
+		if s.Id == nil || s.State == nil {
+			continue
+		}
+		next.Stages[*s.Id] = *s.State
+
+		if buildStateFinished(*s.State) && !buildStateFinished(prev.Stages[*s.Id]) {
+			e := event(WatchEventStageFinished, prev.Stages[*s.Id])
+			e.Stage = s
+			events = append(events, e)
+		}
+	}
+
+	return events
+}
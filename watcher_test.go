@@ -0,0 +1,280 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testWatchEvents(events []*WatchEvent) []string {
+	var got []string
+	for _, e := range events {
+		s := fmt.Sprintf("%s %s #%d", e.Type, e.RepoSlug, *e.Build.Id)
+		if e.Job != nil {
+			s += fmt.Sprintf(" job %d %s->%s", *e.Job.Id, e.PreviousState, *e.Job.State)
+		}
+		if e.Stage != nil {
+			s += fmt.Sprintf(" stage %d", *e.Stage.Id)
+		}
+		got = append(got, s)
+	}
+	return got
+}
+
+func TestWatcher_Poll(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var body string
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/builds", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		testFormValues(t, r, values{"limit": "25", "include": "build.jobs,build.stages"})
+		fmt.Fprintf(w, `{"builds":%s}`, body)
+	})
+
+	w := NewWatcher(client, &WatcherOption{RepoSlugs: []string{testRepoSlug}})
+
+	polls := []struct {
+		body string
+		want []string
+	}{
+		{
+			`[{"id":1,"state":"passed"}]`,
+			nil,
+		},
+		{
+			`[{"id":2,"state":"created","jobs":[{"id":20,"state":"created"}]},{"id":1,"state":"passed"}]`,
+			[]string{"build_created shuheiktgw/go-travis-test #2"},
+		},
+		{
+			`[{"id":2,"state":"started","jobs":[{"id":20,"state":"started"}],"stages":[{"id":200,"state":"started"}]},{"id":1,"state":"passed"}]`,
+			[]string{
+				"build_started shuheiktgw/go-travis-test #2",
+				"job_state_changed shuheiktgw/go-travis-test #2 job 20 created->started",
+			},
+		},
+		{
+			`[{"id":2,"state":"failed","started_at":"2020-01-01T00:00:00Z","jobs":[{"id":20,"state":"failed"}],"stages":[{"id":200,"state":"failed"}]},{"id":1,"state":"passed"}]`,
+			[]string{
+				"build_finished shuheiktgw/go-travis-test #2",
+				"job_state_changed shuheiktgw/go-travis-test #2 job 20 started->failed",
+				"stage_finished shuheiktgw/go-travis-test #2 stage 200",
+			},
+		},
+		{
+			`[{"id":2,"state":"failed"},{"id":1,"state":"created"}]`,
+			[]string{"build_created shuheiktgw/go-travis-test #1"},
+		},
+	}
+
+	for i, p := range polls {
+		body = p.body
+		events, _, err := w.Poll(context.Background())
+		if err != nil {
+			t.Fatalf("#%d Watcher.Poll returned error: %v", i, err)
+		}
+		if got := testWatchEvents(events); !reflect.DeepEqual(got, p.want) {
+			t.Errorf("#%d Watcher.Poll returned %q, want %q", i, got, p.want)
+		}
+	}
+}
+
+func TestWatcher_Poll_active(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	active := `[{"id":3,"state":"started","repository":{"slug":"shuheiktgw/go-travis-test"}}]`
+	mux.HandleFunc(fmt.Sprintf("/owner/%s/active", testOwner), func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		testFormValues(t, r, values{"include": "build.jobs,build.stages"})
+		fmt.Fprintf(w, `{"builds":%s}`, active)
+	})
+	mux.HandleFunc("/build/3", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, `{"id":3,"state":"passed","started_at":"2020-01-01T00:00:00Z","repository":{"slug":"shuheiktgw/go-travis-test"}}`)
+	})
+
+	w := NewWatcher(client, &WatcherOption{Owner: testOwner})
+	if _, _, err := w.Poll(context.Background()); err != nil {
+		t.Fatalf("Watcher.Poll returned error: %v", err)
+	}
+
+	// The finished build is not active anymore
+	active = `[]`
+	events, _, err := w.Poll(context.Background())
+	if err != nil {
+		t.Fatalf("Watcher.Poll returned error: %v", err)
+	}
+
+	want := []string{"build_finished shuheiktgw/go-travis-test #3"}
+	if got := testWatchEvents(events); !reflect.DeepEqual(got, want) {
+		t.Errorf("Watcher.Poll returned %q, want %q", got, want)
+	}
+	if state := w.State(); len(state.Builds) != 1 {
+		t.Errorf("Watcher.State returned %+v, want the finished build", state)
+	}
+}
+
+func TestWatcher_Restore(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/builds", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"builds":[{"id":1,"state":"passed","started_at":"2020-01-01T00:00:00Z"}]}`)
+	})
+
+	saved, err := json.Marshal(&WatchState{Builds: map[uint]string{1: BuildStateCreated}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var state WatchState
+	if err := json.Unmarshal(saved, &state); err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWatcher(client, &WatcherOption{RepoSlugs: []string{testRepoSlug}})
+	w.Restore(&state)
+
+	events, _, err := w.Poll(context.Background())
+	if err != nil {
+		t.Fatalf("Watcher.Poll returned error: %v", err)
+	}
+
+	want := []string{
+		"build_started shuheiktgw/go-travis-test #1",
+		"build_finished shuheiktgw/go-travis-test #1",
+	}
+	if got := testWatchEvents(events); !reflect.DeepEqual(got, want) {
+		t.Errorf("Watcher.Poll returned %q, want %q", got, want)
+	}
+}
+
+func TestWatcher_Poll_stateNotBlocked(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/builds", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"builds":[]}`)
+	})
+	started, release := make(chan struct{}), make(chan struct{})
+	mux.HandleFunc("/build/1", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		fmt.Fprint(w, `{"id":1,"state":"passed","started_at":"2020-01-01T00:00:00Z","repository":{"slug":"shuheiktgw/go-travis-test"}}`)
+	})
+
+	w := NewWatcher(client, &WatcherOption{RepoSlugs: []string{testRepoSlug}})
+	w.Restore(&WatchState{Builds: map[uint]string{1: BuildStateStarted}})
+
+	done := make(chan []*WatchEvent)
+	go func() {
+		events, _, err := w.Poll(context.Background())
+		if err != nil {
+			t.Errorf("Watcher.Poll returned error: %v", err)
+		}
+		done <- events
+	}()
+
+	// The state can be read while the missing build is fetched
+	<-started
+	if state := w.State(); state.Builds[1] != BuildStateStarted {
+		t.Errorf("Watcher.State returned %+v while polling", state)
+	}
+	close(release)
+
+	want := []string{"build_finished shuheiktgw/go-travis-test #1"}
+	if got := testWatchEvents(<-done); !reflect.DeepEqual(got, want) {
+		t.Errorf("Watcher.Poll returned %q, want %q", got, want)
+	}
+}
+
+func TestWatcher_Run(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	polls := 0
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/builds", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		if polls == 1 {
+			fmt.Fprint(w, `{"builds":[]}`)
+			return
+		}
+		fmt.Fprint(w, `{"builds":[{"id":1,"state":"created"}]}`)
+	})
+
+	w := NewWatcher(client, &WatcherOption{RepoSlugs: []string{testRepoSlug}, MinInterval: time.Millisecond})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	events := make(chan *WatchEvent)
+	done := make(chan error)
+	go func() { done <- w.Run(ctx, events) }()
+
+	select {
+	case e := <-events:
+		if e.Type != WatchEventBuildCreated || *e.Build.Id != 1 {
+			t.Errorf("Watcher.Run emitted %+v", e)
+		}
+	case err := <-done:
+		t.Fatalf("Watcher.Run returned %v before emitting", err)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Watcher.Run returned %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestWatcher_Run_canceled(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	polls := 0
+	mux.HandleFunc(fmt.Sprintf("/repo/%s/builds", testRepoSlug), func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		if polls == 1 {
+			fmt.Fprint(w, `{"builds":[]}`)
+			return
+		}
+		fmt.Fprint(w, `{"builds":[{"id":1,"state":"created"},{"id":2,"state":"created"}]}`)
+	})
+
+	w := NewWatcher(client, &WatcherOption{RepoSlugs: []string{testRepoSlug}, MinInterval: time.Millisecond})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	events := make(chan *WatchEvent)
+	done := make(chan error)
+	go func() { done <- w.Run(ctx, events) }()
+
+	// Cancel before the second event of the poll is sent
+	<-events
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("Watcher.Run returned %v, want %v", err, context.Canceled)
+	}
+
+	// The state did not advance, so no event is lost after restarting
+	restarted := NewWatcher(client, &WatcherOption{RepoSlugs: []string{testRepoSlug}})
+	restarted.Restore(w.State())
+
+	got, _, err := restarted.Poll(context.Background())
+	if err != nil {
+		t.Fatalf("Watcher.Poll returned error: %v", err)
+	}
+	if len(got) != 2 || *got[0].Build.Id != 1 || *got[1].Build.Id != 2 {
+		t.Errorf("Watcher.Poll returned %+v, want the events of builds 1 and 2", got)
+	}
+}
@@ -0,0 +1,389 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// WebhookPayload is the payload of a webhook notification sent by Travis CI
+//
+// Travis CI docs: https://docs.travis-ci.com/user/notifications/#webhooks-delivery-format
+type WebhookPayload struct {
+	// The build id
+	Id *uint `json:"id,omitempty"`
+	// The build number
+	Number *string `json:"number,omitempty"`
+	// The event which triggered the build: push, pull_request, cron or api
+	Type *string `json:"type,omitempty"`
+	// The state of the build
+	State *string `json:"state,omitempty"`
+	// The status of the build: 0 when passed, 1 otherwise
+	Status *int `json:"status,omitempty"`
+	// The result of the build, same as status
+	Result *int `json:"result,omitempty"`
+	// The status of the build compared to the previous one, see WebhookEventType
+	StatusMessage *string `json:"status_message,omitempty"`
+	// The result of the build compared to the previous one, same as status_message
+	ResultMessage *string `json:"result_message,omitempty"`
+	// When the build started
+	StartedAt *string `json:"started_at,omitempty"`
+	// When the build finished
+	FinishedAt *string `json:"finished_at,omitempty"`
+	// Wall clock time in seconds
+	Duration *int64 `json:"duration,omitempty"`
+	// The URL of the build
+	BuildUrl *string `json:"build_url,omitempty"`
+	// The id of the commit
+	CommitId *uint `json:"commit_id,omitempty"`
+	// The sha of the commit
+	Commit *string `json:"commit,omitempty"`
+	// The sha of the base commit of the pull request
+	BaseCommit *string `json:"base_commit,omitempty"`
+	// The sha of the head commit of the pull request
+	HeadCommit *string `json:"head_commit,omitempty"`
+	// The branch of the build
+	Branch *string `json:"branch,omitempty"`
+	// The message of the commit
+	Message *string `json:"message,omitempty"`
+	// The URL of the commit's diff on GitHub
+	CompareUrl *string `json:"compare_url,omitempty"`
+	// The commit date
+	CommittedAt    *string `json:"committed_at,omitempty"`
+	AuthorName     *string `json:"author_name,omitempty"`
+	AuthorEmail    *string `json:"author_email,omitempty"`
+	CommitterName  *string `json:"committer_name,omitempty"`
+	CommitterEmail *string `json:"committer_email,omitempty"`
+	// Whether the build is for a pull request
+	PullRequest *bool `json:"pull_request,omitempty"`
+	// The number of the pull request
+	PullRequestNumber *uint `json:"pull_request_number,omitempty"`
+	// The title of the pull request
+	PullRequestTitle *string `json:"pull_request_title,omitempty"`
+	// The tag of the build
+	Tag *string `json:"tag,omitempty"`
+	// The repository of the build
+	Repository *WebhookRepository `json:"repository,omitempty"`
+	// The jobs of the build
+	Matrix []*WebhookJob `json:"matrix,omitempty"`
+	// The build configuration
+	Config map[string]interface{} `json:"config,omitempty"`
+}
+
+// WebhookRepository is the repository of a webhook notification
+type WebhookRepository struct {
+	Id        *uint   `json:"id,omitempty"`
+	Name      *string `json:"name,omitempty"`
+	OwnerName *string `json:"owner_name,omitempty"`
+	Url       *string `json:"url,omitempty"`
+}
+
+// WebhookJob is a job of a webhook notification
+type WebhookJob struct {
+	Id           *uint   `json:"id,omitempty"`
+	Number       *string `json:"number,omitempty"`
+	State        *string `json:"state,omitempty"`
+	Status       *int    `json:"status,omitempty"`
+	Result       *int    `json:"result,omitempty"`
+	StartedAt    *string `json:"started_at,omitempty"`
+	FinishedAt   *string `json:"finished_at,omitempty"`
+	AllowFailure *bool   `json:"allow_failure,omitempty"`
+}
+
+// ToRepository converts the repository of a notification to a minimal Repository
+func (r *WebhookRepository) ToRepository() *Repository {
+	repo := &Repository{Id: r.Id, Name: r.Name}
+	if r.OwnerName != nil && r.Name != nil {
+		repo.Slug = String(*r.OwnerName + "/" + *r.Name)
+	}
+	return repo
+}
+
+// ToJob converts a job of a notification to a minimal Job
+func (j *WebhookJob) ToJob() *Job {
+	return &Job{
+		Id:           j.Id,
+		Number:       j.Number,
+		State:        j.State,
+		StartedAt:    j.StartedAt,
+		FinishedAt:   j.FinishedAt,
+		AllowFailure: j.AllowFailure,
+	}
+}
+
+// ToCommit converts the commit of a notification to a Commit
+func (p *WebhookPayload) ToCommit() *Commit {
+	commit := &Commit{
+		Id:          p.CommitId,
+		Sha:         p.Commit,
+		Message:     p.Message,
+		CompareUrl:  p.CompareUrl,
+		CommittedAt: p.CommittedAt,
+	}
+	if p.CommitterName != nil {
+		commit.Committer = &Committer{Name: *p.CommitterName}
+	}
+	if p.AuthorName != nil {
+		commit.Author = &Author{Name: *p.AuthorName}
+	}
+	return commit
+}
+
+// ToBuild converts a notification to a Build
+func (p *WebhookPayload) ToBuild() *Build {
+	build := &Build{
+		Id:                p.Id,
+		Number:            p.Number,
+		State:             p.State,
+		Duration:          p.Duration,
+		EventType:         p.Type,
+		PullRequestTitle:  p.PullRequestTitle,
+		PullRequestNumber: p.PullRequestNumber,
+		StartedAt:         p.StartedAt,
+		FinishedAt:        p.FinishedAt,
+		Commit:            p.ToCommit(),
+	}
+	if p.Repository != nil {
+		build.Repository = p.Repository.ToRepository()
+	}
+	if p.Branch != nil {
+		build.Branch = &Branch{Name: p.Branch}
+	}
+	if p.Tag != nil {
+		build.Tag = &Tag{Name: p.Tag}
+	}
+	for _, j := range p.Matrix {
+		build.Jobs = append(build.Jobs, j.ToJob())
+	}
+	return build
+}
+
+// WebhookEventType is the type of a webhook notification,
+// given by its status message
+type WebhookEventType string
+
+// Types of webhook notifications
+const (
+	WebhookEventPending      WebhookEventType = "Pending"
+	WebhookEventPassed       WebhookEventType = "Passed"
+	WebhookEventFixed        WebhookEventType = "Fixed"
+	WebhookEventBroken       WebhookEventType = "Broken"
+	WebhookEventFailed       WebhookEventType = "Failed"
+	WebhookEventStillFailing WebhookEventType = "Still Failing"
+	WebhookEventCanceled     WebhookEventType = "Canceled"
+	WebhookEventErrored      WebhookEventType = "Errored"
+)
+
+// EventType returns the type of the notification
+func (p *WebhookPayload) EventType() WebhookEventType {
+	if p.StatusMessage == nil {
+		return ""
+	}
+	return WebhookEventType(*p.StatusMessage)
+}
+
+// WebhookFunc handles a verified webhook notification
+type WebhookFunc func(ctx context.Context, payload *WebhookPayload)
+
+// ErrInvalidSignature is returned when a webhook notification
+// is not signed by the Travis CI server
+var ErrInvalidSignature = errors.New("travis: invalid webhook signature")
+
+const (
+	// DefaultWebhookKeyTTL is how long the public key verifying webhook
+	// notifications is cached by default
+	DefaultWebhookKeyTTL = 24 * time.Hour
+	// webhookKeyMinRefreshInterval limits how often a failed verification
+	// refreshes the public key, so forged requests cannot flood the API
+	webhookKeyMinRefreshInterval = time.Minute
+)
+
+// WebhookHandler is an http.Handler receiving webhook notifications.
+// It verifies their signature with the public key of the Travis CI server,
+// and dispatches them to the functions registered for their type.
+//
+// The public key is fetched with ConfigService and cached for KeyTTL.
+// It is also refreshed when a signature fails to verify, in case it was rotated.
+type WebhookHandler struct {
+	// How long the public key is cached, DefaultWebhookKeyTTL when 0
+	KeyTTL time.Duration
+
+	client *Client
+
+	mu        sync.RWMutex
+	handlers  map[WebhookEventType][]WebhookFunc
+	any       []WebhookFunc
+	key       *rsa.PublicKey
+	fetchedAt time.Time
+
+	// fetchMu serializes the fetches of the public key, without blocking
+	// the verifications with the cached key and the dispatch on mu
+	fetchMu sync.Mutex
+
+	// now returns the current time, replaced in tests
+	now func() time.Time
+}
+
+// NewWebhookHandler returns a WebhookHandler fetching the public key with client
+func NewWebhookHandler(client *Client) *WebhookHandler {
+	return &WebhookHandler{
+		client:   client,
+		handlers: map[WebhookEventType][]WebhookFunc{},
+		now:      time.Now,
+	}
+}
+
+// On registers f to handle the notifications of the given type
+func (h *WebhookHandler) On(typ WebhookEventType, f WebhookFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.handlers[typ] = append(h.handlers[typ], f)
+}
+
+// OnAny registers f to handle all the notifications
+func (h *WebhookHandler) OnAny(f WebhookFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.any = append(h.any, f)
+}
+
+// ServeHTTP verifies and dispatches a webhook notification.
+// It answers 401 Unauthorized when the signature is invalid,
+// and 400 Bad Request when the payload is malformed.
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	payload := r.PostFormValue("payload")
+	if payload == "" {
+		http.Error(w, "missing payload", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Verify(r.Context(), []byte(payload), r.Header.Get("Signature")); err != nil {
+		if err == ErrInvalidSignature {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		} else {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+		}
+		return
+	}
+
+	var p WebhookPayload
+	if err := json.Unmarshal([]byte(payload), &p); err != nil {
+		http.Error(w, "malformed payload", http.StatusBadRequest)
+		return
+	}
+
+	h.mu.RLock()
+	handlers := append(append([]WebhookFunc{}, h.any...), h.handlers[p.EventType()]...)
+	h.mu.RUnlock()
+
+	for _, f := range handlers {
+		f(r.Context(), &p)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Verify verifies the base64 encoded RSA-SHA1 signature of a payload.
+// It returns ErrInvalidSignature if the signature is invalid,
+// or an error if the public key could not be fetched.
+func (h *WebhookHandler) Verify(ctx context.Context, payload []byte, signature string) error {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil || len(sig) == 0 {
+		return ErrInvalidSignature
+	}
+
+	digest := sha1.Sum(payload)
+
+	key, err := h.publicKey(ctx, false)
+	if err != nil {
+		return err
+	}
+	if rsa.VerifyPKCS1v15(key, crypto.SHA1, digest[:], sig) == nil {
+		return nil
+	}
+
+	// The key may have been rotated since it was fetched
+	refreshed, err := h.publicKey(ctx, true)
+	if err != nil {
+		return err
+	}
+	if refreshed != key && rsa.VerifyPKCS1v15(refreshed, crypto.SHA1, digest[:], sig) == nil {
+		return nil
+	}
+
+	return ErrInvalidSignature
+}
+
+// publicKey returns the cached public key, fetching it when it expired,
+// or when refresh is set and it was not fetched recently
+func (h *WebhookHandler) publicKey(ctx context.Context, refresh bool) (*rsa.PublicKey, error) {
+	if key, ok := h.cachedPublicKey(refresh); ok {
+		return key, nil
+	}
+
+	h.fetchMu.Lock()
+	defer h.fetchMu.Unlock()
+
+	// Another call may have fetched the key while this one was waiting
+	if key, ok := h.cachedPublicKey(refresh); ok {
+		return key, nil
+	}
+
+	config, _, err := h.client.Config.Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil || config.Notifications == nil || config.Notifications.Webhook == nil || config.Notifications.Webhook.PublicKey == nil {
+		return nil, errors.New("travis: the server config has no webhook public key")
+	}
+
+	key, err := parsePublicKey(*config.Notifications.Webhook.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	h.mu.Lock()
+	h.key = key
+	h.fetchedAt = h.now()
+	h.mu.Unlock()
+
+	return key, nil
+}
+
+// cachedPublicKey returns the cached public key unless it has to be fetched
+func (h *WebhookHandler) cachedPublicKey(refresh bool) (*rsa.PublicKey, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	ttl := h.KeyTTL
+	if ttl == 0 {
+		ttl = DefaultWebhookKeyTTL
+	}
+
+	age := h.now().Sub(h.fetchedAt)
+	if h.key != nil && age < ttl && (!refresh || age < webhookKeyMinRefreshInterval) {
+		return h.key, true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,263 @@
+// Copyright (c) 2015 Ableton AG, Berlin. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package travis
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testWebhookPayload = `{
+  "id":1,
+  "number":"25",
+  "type":"push",
+  "state":"passed",
+  "status":0,
+  "status_message":"Fixed",
+  "duration":120,
+  "commit_id":2,
+  "commit":"62aae5f70ceee39123ef",
+  "branch":"master",
+  "message":"the commit message",
+  "committer_name":"shuheiktgw",
+  "author_name":"shuheiktgw",
+  "repository":{"id":3,"name":"go-travis-test","owner_name":"shuheiktgw"},
+  "matrix":[{"id":4,"number":"25.1","state":"passed","allow_failure":false}]
+}`
+
+func testWebhookSign(t *testing.T, key *rsa.PrivateKey, payload string) string {
+	digest := sha1.Sum([]byte(payload))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA1, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+func testWebhookRequest(payload, signature string) *http.Request {
+	form := url.Values{"payload": {payload}}
+	r := httptest.NewRequest(http.MethodPost, "/travis", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("Signature", signature)
+	return r
+}
+
+func testWebhookConfig(t *testing.T, mux *http.ServeMux, key **rsa.PrivateKey) *int {
+	fetched := 0
+	mux.HandleFunc("/config", func(w http.ResponseWriter, r *http.Request) {
+		fetched++
+		fmt.Fprintf(w, `{"config":{"notifications":{"webhook":{"public_key":%q}}}}`, testPublicKeyPEM(t, *key, "PUBLIC KEY", false))
+	})
+	return &fetched
+}
+
+func TestWebhookHandler_ServeHTTP(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	key := testRSAKey(t)
+	fetched := testWebhookConfig(t, mux, &key)
+
+	h := NewWebhookHandler(client)
+
+	var got []string
+	h.OnAny(func(ctx context.Context, p *WebhookPayload) {
+		got = append(got, "any "+*p.Number)
+	})
+	h.On(WebhookEventFixed, func(ctx context.Context, p *WebhookPayload) {
+		got = append(got, "fixed "+*p.Number)
+	})
+	h.On(WebhookEventBroken, func(ctx context.Context, p *WebhookPayload) {
+		got = append(got, "broken "+*p.Number)
+	})
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, testWebhookRequest(testWebhookPayload, testWebhookSign(t, key, testWebhookPayload)))
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("WebhookHandler.ServeHTTP returned %d: %s", w.Code, w.Body)
+		}
+	}
+
+	want := []string{"any 25", "fixed 25", "any 25", "fixed 25"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WebhookHandler dispatched %v, want %v", got, want)
+	}
+
+	if *fetched != 1 {
+		t.Errorf("WebhookHandler fetched the public key %d times, want 1", *fetched)
+	}
+}
+
+func TestWebhookHandler_ServeHTTP_invalid(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	key := testRSAKey(t)
+	testWebhookConfig(t, mux, &key)
+
+	h := NewWebhookHandler(client)
+	h.OnAny(func(ctx context.Context, p *WebhookPayload) {
+		t.Error("WebhookHandler dispatched an invalid notification")
+	})
+
+	signature := testWebhookSign(t, key, testWebhookPayload)
+	cases := []struct {
+		r    *http.Request
+		want int
+	}{
+		{testWebhookRequest(strings.Replace(testWebhookPayload, "passed", "failed", 1), signature), http.StatusUnauthorized},
+		{testWebhookRequest(testWebhookPayload, "not base64"), http.StatusUnauthorized},
+		{testWebhookRequest(testWebhookPayload, ""), http.StatusUnauthorized},
+		{testWebhookRequest("", signature), http.StatusBadRequest},
+		{testWebhookRequest("{", testWebhookSign(t, key, "{")), http.StatusBadRequest},
+		{httptest.NewRequest(http.MethodGet, "/travis", nil), http.StatusMethodNotAllowed},
+	}
+
+	for i, c := range cases {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, c.r)
+		if w.Code != c.want {
+			t.Errorf("#%d WebhookHandler.ServeHTTP returned %d, want %d", i, w.Code, c.want)
+		}
+	}
+}
+
+func TestWebhookHandler_keyRotation(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	key := testRSAKey(t)
+	fetched := testWebhookConfig(t, mux, &key)
+
+	now := time.Now()
+	h := NewWebhookHandler(client)
+	h.now = func() time.Time { return now }
+
+	if err := h.Verify(context.Background(), []byte("payload"), testWebhookSign(t, key, "payload")); err != nil {
+		t.Fatalf("WebhookHandler.Verify returned error: %v", err)
+	}
+
+	rotated, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key = rotated
+
+	// The key was just fetched, so it is not refreshed yet
+	if err := h.Verify(context.Background(), []byte("payload"), testWebhookSign(t, rotated, "payload")); err != ErrInvalidSignature {
+		t.Errorf("WebhookHandler.Verify returned %v, want %v", err, ErrInvalidSignature)
+	}
+
+	now = now.Add(2 * webhookKeyMinRefreshInterval)
+	if err := h.Verify(context.Background(), []byte("payload"), testWebhookSign(t, rotated, "payload")); err != nil {
+		t.Errorf("WebhookHandler.Verify returned error: %v", err)
+	}
+
+	if *fetched != 2 {
+		t.Errorf("WebhookHandler fetched the public key %d times, want 2", *fetched)
+	}
+}
+
+func TestWebhookHandler_concurrentFetch(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	key := testRSAKey(t)
+	publicKey := testPublicKeyPEM(t, key, "PUBLIC KEY", false)
+
+	var fetched int32
+	started, release := make(chan struct{}), make(chan struct{})
+	mux.HandleFunc("/config", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&fetched, 1) == 1 {
+			close(started)
+		}
+		<-release
+		fmt.Fprintf(w, `{"config":{"notifications":{"webhook":{"public_key":%q}}}}`, publicKey)
+	})
+
+	h := NewWebhookHandler(client)
+	signature := testWebhookSign(t, key, "payload")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- h.Verify(context.Background(), []byte("payload"), signature)
+		}()
+	}
+
+	// Registering handlers is not blocked while the key is fetched
+	<-started
+	h.OnAny(func(context.Context, *WebhookPayload) {})
+
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("WebhookHandler.Verify returned error: %v", err)
+		}
+	}
+
+	if fetched != 1 {
+		t.Errorf("WebhookHandler fetched the public key %d times, want 1", fetched)
+	}
+}
+
+func TestWebhookPayload_ToBuild(t *testing.T) {
+	var p WebhookPayload
+	if err := json.Unmarshal([]byte(testWebhookPayload), &p); err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Build{
+		Id:        Uint(1),
+		Number:    String("25"),
+		State:     String("passed"),
+		Duration:  Int64(120),
+		EventType: String("push"),
+		Repository: &Repository{
+			Id:   Uint(3),
+			Name: String("go-travis-test"),
+			Slug: String("shuheiktgw/go-travis-test"),
+		},
+		Branch: &Branch{Name: String("master")},
+		Commit: &Commit{
+			Id:        Uint(2),
+			Sha:       String("62aae5f70ceee39123ef"),
+			Message:   String("the commit message"),
+			Committer: &Committer{Name: "shuheiktgw"},
+			Author:    &Author{Name: "shuheiktgw"},
+		},
+		Jobs: []*Job{{Id: Uint(4), Number: String("25.1"), State: String("passed"), AllowFailure: Bool(false)}},
+	}
+
+	if got := p.ToBuild(); !reflect.DeepEqual(got, want) {
+		t.Errorf("WebhookPayload.ToBuild returned %+v, want %+v", got, want)
+	}
+	if got := p.EventType(); got != WebhookEventFixed {
+		t.Errorf("WebhookPayload.EventType returned %q, want %q", got, WebhookEventFixed)
+	}
+}